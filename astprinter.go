@@ -21,10 +21,42 @@ func (ap *AstPrinter) VisitAssignExpr(expr *Assign) (interface{}, error) {
 	return ap.parenthesize("=", &Literal{Value: expr.Name.Lexeme}, &Literal{Value: rightStr}), nil
 }
 
+func (ap *AstPrinter) VisitCompoundAssignExpr(expr *CompoundAssign) (interface{}, error) {
+	return ap.parenthesize(expr.Operator.Lexeme, expr.Target, expr.Value), nil
+}
+
+func (ap *AstPrinter) VisitUpdateExpr(expr *Update) (interface{}, error) {
+	if expr.Prefix {
+		return ap.parenthesize("pre"+expr.Operator.Lexeme, expr.Target), nil
+	}
+	return ap.parenthesize("post"+expr.Operator.Lexeme, expr.Target), nil
+}
+
 func (ap *AstPrinter) VisitBinaryExpr(expr *Binary) (interface{}, error) {
 	return ap.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right), nil
 }
 
+func (ap *AstPrinter) VisitCallExpr(expr *Call) (interface{}, error) {
+	name := "call"
+	if expr.Optional {
+		name = "?.call"
+	}
+	arguments := append([]Expr{expr.Callee}, expr.Arguments...)
+	if expr.Names != nil {
+		for n, argName := range expr.Names {
+			if argName != nil {
+				valueStr, _ := expr.Arguments[n].Accept(ap)
+				arguments[n+1] = &Literal{Value: argName.Lexeme + ": " + valueStr.(string)}
+			}
+		}
+	}
+	return ap.parenthesize(name, arguments...), nil
+}
+
+func (ap *AstPrinter) VisitConditionalExpr(expr *Conditional) (interface{}, error) {
+	return ap.parenthesize("?:", expr.Condition, expr.Then, expr.Else), nil
+}
+
 func (ap *AstPrinter) VisitBlockStmt(stmt *Block) (interface{}, error) {
 	var buf bytes.Buffer
 	buf.WriteString("(block")
@@ -76,6 +108,19 @@ func (ap *AstPrinter) VisitForStmt(stmt *For) (interface{}, error) {
 	return builder.String(), nil
 }
 
+func (ap *AstPrinter) VisitForInStmt(stmt *ForIn) (interface{}, error) {
+	iterableStr, _ := stmt.Iterable.Accept(ap)
+	bodyStr, _ := stmt.Body.Accept(ap)
+	return fmt.Sprintf("(for %s in %s %s)", stmt.Pattern, iterableStr, bodyStr), nil
+}
+
+func (ap *AstPrinter) VisitGetExpr(expr *Get) (interface{}, error) {
+	if expr.Optional {
+		return ap.parenthesize("?."+expr.Name.Lexeme, expr.Object), nil
+	}
+	return ap.parenthesize("."+expr.Name.Lexeme, expr.Object), nil
+}
+
 func (ap *AstPrinter) VisitGroupingExpr(expr *Grouping) (interface{}, error) {
 	return ap.parenthesize("group", expr.Expression), nil
 }
@@ -97,17 +142,84 @@ func (ap *AstPrinter) VisitIfStmt(stmt *If) (interface{}, error) {
 	return buf.String(), nil
 }
 
+func (ap *AstPrinter) VisitLambdaExpr(expr *Lambda) (interface{}, error) {
+	var buf bytes.Buffer
+	buf.WriteString("(fun ")
+	if expr.Name != nil {
+		buf.WriteString(expr.Name.Lexeme + " ")
+	}
+	buf.WriteString("(")
+	for n, param := range expr.Params {
+		if n > 0 {
+			buf.WriteString(" ")
+		}
+		if param.Rest {
+			buf.WriteString("...")
+		}
+		buf.WriteString(param.String())
+		if param.Default != nil {
+			defaultStr, _ := param.Default.Accept(ap)
+			buf.WriteString("=" + defaultStr.(string))
+		}
+	}
+	buf.WriteString(")")
+	for _, stmt := range expr.Body {
+		stmtStr, _ := stmt.Accept(ap)
+		buf.WriteString(" ")
+		buf.WriteString(stmtStr.(string))
+	}
+	buf.WriteString(")")
+	return buf.String(), nil
+}
+
 func (ap *AstPrinter) VisitLiteralExpr(expr *Literal) (interface{}, error) {
 	if expr.Value == nil {
 		return "nil", nil
 	}
+	if expr.Lexeme != "" {
+		return expr.Lexeme, nil
+	}
 	return fmt.Sprintf("%v", expr.Value), nil
 }
 
+func (ap *AstPrinter) VisitLogicalExpr(expr *Logical) (interface{}, error) {
+	return ap.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right), nil
+}
+
+func (ap *AstPrinter) VisitMatchExpr(expr *Match) (interface{}, error) {
+	var buf bytes.Buffer
+	subjectStr, _ := expr.Subject.Accept(ap)
+	buf.WriteString("(match " + subjectStr.(string))
+	for _, arm := range expr.Arms {
+		buf.WriteString(" (" + arm.Pattern.String())
+		if arm.Guard != nil {
+			guardStr, _ := arm.Guard.Accept(ap)
+			buf.WriteString(" if " + guardStr.(string))
+		}
+		var bodyStr interface{}
+		if arm.Body != nil {
+			bodyStr, _ = arm.Body.Accept(ap)
+		} else {
+			bodyStr, _ = arm.Value.Accept(ap)
+		}
+		buf.WriteString(" " + bodyStr.(string) + ")")
+	}
+	buf.WriteString(")")
+	return buf.String(), nil
+}
+
+func (ap *AstPrinter) VisitOptionalChainExpr(expr *OptionalChain) (interface{}, error) {
+	return expr.Expression.Accept(ap)
+}
+
 func (ap *AstPrinter) VisitPrintStmt(stmt *Print) (interface{}, error) {
 	return ap.parenthesize("print", stmt.Expression), nil
 }
 
+func (ap *AstPrinter) VisitSetExpr(expr *Set) (interface{}, error) {
+	return ap.parenthesize("=."+expr.Name.Lexeme, expr.Object, expr.Value), nil
+}
+
 func (ap *AstPrinter) VisitUnaryExpr(expr *Unary) (interface{}, error) {
 	return ap.parenthesize(expr.Operator.Lexeme, expr.Right), nil
 }
@@ -129,11 +241,97 @@ func (ap *AstPrinter) VisitWhileStmt(stmt *While) (interface{}, error) {
 }
 
 func (ap *AstPrinter) VisitVarStmt(stmt *Var) (interface{}, error) {
+	name := ""
+	if stmt.Pattern != nil {
+		name = stmt.Pattern.String()
+	} else {
+		name = stmt.Name.Lexeme
+	}
+	keyword := "var"
+	if stmt.Keyword != nil {
+		keyword = stmt.Keyword.Lexeme
+	} else if stmt.Constant {
+		keyword = "const"
+	}
 	if stmt.Initializer != nil {
 		initStr, _ := stmt.Initializer.Accept(ap)
-		return fmt.Sprintf("(var %s = %s)", stmt.Name.Lexeme, initStr), nil
+		return fmt.Sprintf("(%s %s = %s)", keyword, name, initStr), nil
+	}
+	return fmt.Sprintf("(%s %s)", keyword, name), nil
+}
+
+func (ap *AstPrinter) VisitImportStmt(stmt *Import) (interface{}, error) {
+	return fmt.Sprintf("(import %s as %s)", stmt.Path.Lexeme, stmt.Name.Lexeme), nil
+}
+
+func (ap *AstPrinter) VisitExportStmt(stmt *Export) (interface{}, error) {
+	declStr, _ := stmt.Declaration.Accept(ap)
+	return fmt.Sprintf("(export %s)", declStr), nil
+}
+
+func (ap *AstPrinter) VisitThrowStmt(stmt *Throw) (interface{}, error) {
+	return ap.parenthesize("throw", stmt.Value), nil
+}
+
+func (ap *AstPrinter) VisitTryStmt(stmt *Try) (interface{}, error) {
+	var buf bytes.Buffer
+	buf.WriteString("(try ")
+	bodyStr, _ := stmt.Body.Accept(ap)
+	buf.WriteString(bodyStr.(string))
+	if stmt.CatchBody != nil {
+		catchStr, _ := stmt.CatchBody.Accept(ap)
+		buf.WriteString(fmt.Sprintf(" catch %s %s", stmt.CatchName.Lexeme, catchStr))
+	}
+	if stmt.FinallyBody != nil {
+		finallyStr, _ := stmt.FinallyBody.Accept(ap)
+		buf.WriteString(" finally ")
+		buf.WriteString(finallyStr.(string))
+	}
+	buf.WriteString(")")
+	return buf.String(), nil
+}
+
+func (ap *AstPrinter) VisitDeferStmt(stmt *Defer) (interface{}, error) {
+	stmtStr, _ := stmt.Statement.Accept(ap)
+	return fmt.Sprintf("(defer %s)", stmtStr), nil
+}
+
+func (ap *AstPrinter) VisitReturnStmt(stmt *Return) (interface{}, error) {
+	if stmt.Value == nil {
+		return "(return)", nil
+	}
+	return ap.parenthesize("return", stmt.Value), nil
+}
+
+func (ap *AstPrinter) VisitSwitchStmt(stmt *Switch) (interface{}, error) {
+	var buf bytes.Buffer
+	subjectStr, _ := stmt.Subject.Accept(ap)
+	buf.WriteString("(switch " + subjectStr.(string))
+	for _, clause := range stmt.Cases {
+		if clause.Values == nil {
+			buf.WriteString(" (default")
+		} else {
+			buf.WriteString(" (case")
+			for _, value := range clause.Values {
+				valueStr, _ := value.Accept(ap)
+				buf.WriteString(" " + valueStr.(string))
+			}
+		}
+		for _, s := range clause.Body {
+			stmtStr, _ := s.Accept(ap)
+			buf.WriteString(" " + stmtStr.(string))
+		}
+		if clause.Fallthrough {
+			buf.WriteString(" fallthrough")
+		}
+		buf.WriteString(")")
 	}
-	return fmt.Sprintf("(var %s)", stmt.Name.Lexeme), nil
+	buf.WriteString(")")
+	return buf.String(), nil
+}
+
+func (ap *AstPrinter) VisitBreakStmt(stmt *Break) (interface{}, error) {
+	return "(break)", nil
 }
 
 func (ap *AstPrinter) parenthesize(name string, exprs ...Expr) string {
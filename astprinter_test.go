@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+func TestAstPrinterDeclarations(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`var x = 1;`, "(var x = 1)"},
+		{`var y;`, "(var y)"},
+		{`const K = 0x10;`, "(const K = 0x10)"},
+		{`const [a, b] = pair;`, "(const [a, b] = pair)"},
+		{`export const limit = 3;`, "(export (const limit = 3))"},
+		{`for (var [k, v] in m) print k;`, "(for [k, v] in m (print k))"},
+		{`let x = 1;`, "(let x = 1)"},
+		{`let [a, b] = pair;`, "(let [a, b] = pair)"},
+		{`export let limit = 3;`, "(export (let limit = 3))"},
+		{`const {host, port} = config;`, "(const {host, port} = config)"},
+		{`const [first, [second, ...rest]] = xs;`, "(const [first, [second, ...rest]] = xs)"},
+		{`var [{name}, ...others] = items;`, "(var [{name}, ...others] = items)"},
+	}
+	for _, test := range tests {
+		scanner := NewScanner(test.source)
+		scanner.SetErrorOutput(io.Discard)
+		tokens := scanner.ScanTokens()
+		pointers := make([]*Token, len(tokens))
+		for n := range tokens {
+			pointers[n] = &tokens[n]
+		}
+		parser := NewParser(pointers)
+		parser.SetErrorOutput(io.Discard)
+		statements, err := parser.Parse()
+		if err != nil || len(statements) != 1 {
+			t.Fatalf("%s: Parse() = %v, %v", test.source, statements, err)
+		}
+		got, _ := statements[0].Accept(&AstPrinter{})
+		if got != test.want {
+			t.Errorf("%s: printed %q, want %q", test.source, got, test.want)
+		}
+	}
+}
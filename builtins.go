@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"time"
+)
+
+// Capability is a set of built-in function groups a script may use.
+type Capability int
+
+const (
+	CapabilityFileSystem Capability = 1 << iota
+	CapabilityEnvironment
+	CapabilityExec
+	CapabilityClock
+
+	NoCapabilities  Capability = 0
+	AllCapabilities            = CapabilityFileSystem | CapabilityEnvironment | CapabilityExec | CapabilityClock
+)
+
+func (c Capability) String() string {
+	switch c {
+	case CapabilityFileSystem:
+		return "fs"
+	case CapabilityEnvironment:
+		return "env"
+	case CapabilityExec:
+		return "exec"
+	case CapabilityClock:
+		return "clock"
+	}
+	return fmt.Sprintf("Capability(%d)", int(c))
+}
+
+type builtin struct {
+	capability Capability
+	name       string
+	fn         interface{}
+}
+
+var builtins = []builtin{
+	{NoCapabilities, "freeze", freeze},
+	{CapabilityClock, "clock", func() float64 {
+		return float64(time.Now().UnixNano()) / float64(time.Second)
+	}},
+	{CapabilityClock, "sleep", func(i *Interpreter, seconds float64) error {
+		select {
+		case <-time.After(time.Duration(seconds * float64(time.Second))):
+			return nil
+		case <-i.Context().Done():
+			return i.Context().Err()
+		}
+	}},
+	{CapabilityFileSystem, "readFile", func(path string) (string, error) {
+		bytes, err := os.ReadFile(path)
+		return string(bytes), err
+	}},
+	{CapabilityFileSystem, "writeFile", func(path, content string) error {
+		return os.WriteFile(path, []byte(content), 0644)
+	}},
+	{CapabilityEnvironment, "getenv", os.Getenv},
+	{CapabilityEnvironment, "setenv", os.Setenv},
+	{CapabilityExec, "exec", func(i *Interpreter, name string, args ...string) (string, error) {
+		output, err := exec.CommandContext(i.Context(), name, args...).Output()
+		return string(output), err
+	}},
+}
+
+func (i *Interpreter) defineBuiltins(options Options) {
+	for _, b := range builtins {
+		if b.capability == NoCapabilities || options.Capabilities&b.capability != 0 {
+			function, err := wrapGoFunc(b.name, reflect.ValueOf(b.fn))
+			if err != nil {
+				panic(err)
+			}
+			i.builtins.Define(b.name, function)
+		} else if options.PermissionErrors {
+			i.builtins.Define(b.name, deniedBuiltin(b))
+		}
+	}
+}
+
+func deniedBuiltin(b builtin) *NativeFunction {
+	return NewNativeFunction(b.name, -1, func(*Interpreter, []interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("Permission denied: '%s' requires the %s capability.", b.name, b.capability)
+	})
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		stdout  string
+		stderr  string
+	}{
+		{"denied", Options{}, "", "variable 'clock'"},
+		{"granted", Options{Capabilities: CapabilityClock}, "true\n", ""},
+		{"other capability", Options{Capabilities: CapabilityFileSystem}, "", "variable 'clock'"},
+		{"permission error", Options{PermissionErrors: true}, "", "Permission denied: 'clock' requires the clock capability."},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stdout, stderr, _ := runScript(NewInterpreter(test.options), `print clock() > 0;`)
+			if stdout != test.stdout {
+				t.Errorf("stdout = %q, want %q", stdout, test.stdout)
+			}
+			if !strings.Contains(stderr, test.stderr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr, test.stderr)
+			}
+		})
+	}
+}
+
+func TestFreezeNeedsNoCapability(t *testing.T) {
+	interpreter := NewInterpreter(Options{})
+	if err := interpreter.Define("config", map[string]int{"retries": 3}); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, _ := runScript(interpreter, `freeze(config); print config.retries; config.retries = 4;`)
+	if stdout != "3\n" {
+		t.Errorf("stdout = %q, want %q", stdout, "3\n")
+	}
+	if want := "Cannot assign to 'retries' of a frozen map."; !strings.Contains(stderr, want) {
+		t.Errorf("stderr = %q, want it to contain %q", stderr, want)
+	}
+}
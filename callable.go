@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type Callable interface {
+	// Arity returns the number of arguments the callable expects, or -1 if
+	// it accepts a variable number and checks them itself.
+	Arity() int
+	Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error)
+}
+
+type NativeFunction struct {
+	name  string
+	arity int
+	fn    func(interpreter *Interpreter, arguments []interface{}) (interface{}, error)
+}
+
+func NewNativeFunction(name string, arity int, fn func(*Interpreter, []interface{}) (interface{}, error)) *NativeFunction {
+	return &NativeFunction{name: name, arity: arity, fn: fn}
+}
+
+func (n *NativeFunction) Name() string {
+	return n.name
+}
+
+func (n *NativeFunction) Arity() int {
+	return n.arity
+}
+
+func (n *NativeFunction) Call(interpreter *Interpreter, arguments []interface{}) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("native function '%s' panicked: %v", n.name, r)
+		}
+	}()
+	return n.fn(interpreter, arguments)
+}
+
+func (n *NativeFunction) String() string {
+	return fmt.Sprintf("<native fn %s>", n.name)
+}
+
+var (
+	errorType       = reflect.TypeOf((*error)(nil)).Elem()
+	interpreterType = reflect.TypeOf((*Interpreter)(nil))
+)
+
+// wrapGoFunc adapts an arbitrary Go function into a NativeFunction,
+// converting arguments from Lango values to the parameter types and the
+// results back. A trailing error result is reported as a runtime error,
+// and a leading *Interpreter parameter receives the calling interpreter.
+func wrapGoFunc(name string, fn reflect.Value) (*NativeFunction, error) {
+	fnType := fn.Type()
+	if fnType.Kind() != reflect.Func {
+		return nil, fmt.Errorf("'%s' is not a function", name)
+	}
+
+	numOut := fnType.NumOut()
+	returnsError := numOut > 0 && fnType.Out(numOut-1) == errorType
+	if returnsError {
+		numOut--
+	}
+	if numOut > 1 {
+		return nil, fmt.Errorf("function '%s' must return at most one value and an optional error", name)
+	}
+
+	first := 0
+	if fnType.NumIn() > 0 && fnType.In(0) == interpreterType {
+		first = 1
+	}
+	for n := first; n < fnType.NumIn(); n++ {
+		paramType := fnType.In(n)
+		if fnType.IsVariadic() && n == fnType.NumIn()-1 {
+			paramType = paramType.Elem()
+		}
+		if !isConvertibleType(paramType) {
+			return nil, fmt.Errorf("function '%s' has unsupported parameter type %s", name, paramType)
+		}
+	}
+
+	minArgs := fnType.NumIn() - first
+	arity := minArgs
+	if fnType.IsVariadic() {
+		minArgs--
+		arity = -1
+	}
+
+	call := func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+		if len(arguments) < minArgs {
+			return nil, fmt.Errorf("Expected at least %d arguments but got %d.", minArgs, len(arguments))
+		}
+
+		in := make([]reflect.Value, first, first+len(arguments))
+		if first == 1 {
+			in[0] = reflect.ValueOf(interpreter)
+		}
+		for n, argument := range arguments {
+			var paramType reflect.Type
+			if fnType.IsVariadic() && first+n >= fnType.NumIn()-1 {
+				paramType = fnType.In(fnType.NumIn() - 1).Elem()
+			} else {
+				paramType = fnType.In(first + n)
+			}
+			value, err := fromLango(argument, paramType)
+			if err != nil {
+				return nil, fmt.Errorf("Argument %d to '%s': %s", n+1, name, err.Error())
+			}
+			in = append(in, value)
+		}
+
+		out := fn.Call(in)
+		if returnsError {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return nil, err
+			}
+		}
+		if numOut == 0 {
+			return nil, nil
+		}
+		return toLango(out[0])
+	}
+
+	return NewNativeFunction(name, arity, call), nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type List struct {
+	Elements []interface{}
+	frozen   bool
+}
+
+func NewList(elements []interface{}) *List {
+	return &List{Elements: elements}
+}
+
+type Map struct {
+	Entries map[string]interface{}
+	frozen  bool
+}
+
+func NewMap(entries map[string]interface{}) *Map {
+	if entries == nil {
+		entries = make(map[string]interface{})
+	}
+	return &Map{Entries: entries}
+}
+
+// Keys returns the map's keys in sorted order so that printing and
+// iteration are deterministic.
+func (m *Map) Keys() []string {
+	keys := make([]string, 0, len(m.Entries))
+	for key := range m.Entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *Map) Get(name *Token) (interface{}, error) {
+	return m.Entries[name.Lexeme], nil
+}
+
+func (m *Map) Set(name *Token, value interface{}) error {
+	if m.frozen {
+		return fmt.Errorf("Cannot assign to '%s' of a frozen map.", name.Lexeme)
+	}
+	m.Entries[name.Lexeme] = value
+	return nil
+}
+
+// freeze makes value, and every list and map it contains, read-only. It
+// returns value.
+func freeze(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *List:
+		if !v.frozen {
+			v.frozen = true
+			for _, element := range v.Elements {
+				freeze(element)
+			}
+		}
+	case *Map:
+		if !v.frozen {
+			v.frozen = true
+			for _, entry := range v.Entries {
+				freeze(entry)
+			}
+		}
+	}
+	return value
+}
+
+// stringifyList formats list. seen holds the lists and maps already
+// printed; one reached again, such as a list that contains itself, is
+// printed as [...] or {...}.
+func (i *Interpreter) stringifyList(list *List, seen map[interface{}]bool) string {
+	if seen[list] {
+		return "[...]"
+	}
+	seen[list] = true
+
+	var builder strings.Builder
+	builder.WriteString("[")
+	for n, element := range list.Elements {
+		if n > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(i.stringifyElement(element, seen))
+	}
+	builder.WriteString("]")
+	return builder.String()
+}
+
+func (i *Interpreter) stringifyMap(m *Map, seen map[interface{}]bool) string {
+	if seen[m] {
+		return "{...}"
+	}
+	seen[m] = true
+
+	var builder strings.Builder
+	builder.WriteString("{")
+	for n, key := range m.Keys() {
+		if n > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(key)
+		builder.WriteString(": ")
+		builder.WriteString(i.stringifyElement(m.Entries[key], seen))
+	}
+	builder.WriteString("}")
+	return builder.String()
+}
+
+func (i *Interpreter) stringifyElement(value interface{}, seen map[interface{}]bool) string {
+	switch v := value.(type) {
+	case *List:
+		return i.stringifyList(v, seen)
+	case *Map:
+		return i.stringifyMap(v, seen)
+	}
+	return i.stringify(value)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPrintCyclicCollections(t *testing.T) {
+	interpreter := NewInterpreter(Options{})
+	shared := NewMap(nil)
+	list := NewList([]interface{}{int64(1), shared})
+	shared.Entries["list"] = list
+	if err := interpreter.Define("m", map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := interpreter.Define("xs", list); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, err := runScript(interpreter, `
+		m.self = m;
+		m.n = 1;
+		print m;
+		print xs;
+	`)
+	if err != nil {
+		t.Fatalf("Run() = %v, stderr %q", err, stderr)
+	}
+	if want := "{n: 1, self: {...}}\n[1, {list: [...]}]\n"; stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+}
+
+type tree map[string]tree
+
+func TestConvertCyclicMap(t *testing.T) {
+	interpreter := NewInterpreter(Options{})
+	if err := interpreter.Define("m", map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	err := interpreter.Define("isOwnChild", func(t tree) bool {
+		return reflect.ValueOf(t["self"]).Pointer() == reflect.ValueOf(t).Pointer()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, _ := runScript(interpreter, `m.self = m; print isOwnChild(m);`)
+	if stdout != "true\n" {
+		t.Errorf("stdout = %q, stderr %q, want %q", stdout, stderr, "true\n")
+	}
+}
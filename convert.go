@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+)
+
+var (
+	bigIntType   = reflect.TypeOf((*big.Int)(nil))
+	listType     = reflect.TypeOf((*List)(nil))
+	mapType      = reflect.TypeOf((*Map)(nil))
+	callableType = reflect.TypeOf((*Callable)(nil)).Elem()
+)
+
+// toLango converts a Go value into its Lango representation: integers
+// become int64 (or *big.Int), floats become float64, slices and arrays
+// become lists, string-keyed maps become maps and functions become native
+// functions.
+func toLango(value reflect.Value) (interface{}, error) {
+	if !value.IsValid() {
+		return nil, nil
+	}
+
+	if value.Type() == bigIntType {
+		if value.IsNil() {
+			return nil, nil
+		}
+		return normalizeInt(new(big.Int).Set(value.Interface().(*big.Int))), nil
+	}
+
+	if value.Type() == listType || value.Type() == mapType || value.Type() == goObjectType || value.Type().Implements(callableType) {
+		if value.Kind() == reflect.Ptr && value.IsNil() {
+			return nil, nil
+		}
+		return value.Interface(), nil
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		return value.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return normalizeInt(new(big.Int).SetUint64(value.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), nil
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Interface:
+		if value.IsNil() {
+			return nil, nil
+		}
+		return toLango(value.Elem())
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return nil, nil
+		}
+		elements := make([]interface{}, value.Len())
+		for n := range elements {
+			element, err := toLango(value.Index(n))
+			if err != nil {
+				return nil, err
+			}
+			elements[n] = element
+		}
+		return NewList(elements), nil
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", value.Type().Key())
+		}
+		if value.IsNil() {
+			return nil, nil
+		}
+		entries := make(map[string]interface{}, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			entry, err := toLango(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			entries[iter.Key().String()] = entry
+		}
+		return NewMap(entries), nil
+	case reflect.Func:
+		if value.IsNil() {
+			return nil, nil
+		}
+		return wrapGoFunc("anonymous", value)
+	case reflect.Ptr:
+		if value.IsNil() {
+			return nil, nil
+		}
+		if value.Elem().Kind() == reflect.Struct {
+			return newGoObject(value)
+		}
+	case reflect.Struct:
+		return newGoObject(value)
+	}
+
+	return nil, fmt.Errorf("unsupported Go type %s", value.Type())
+}
+
+// conversion identifies a list or map converted to a Go type.
+type conversion struct {
+	collection interface{}
+	target     reflect.Type
+}
+
+// fromLango converts a Lango value into a Go value of the given type.
+func fromLango(value interface{}, target reflect.Type) (reflect.Value, error) {
+	return convertFromLango(value, target, make(map[conversion]reflect.Value))
+}
+
+// convertFromLango is fromLango with the lists and maps already converted
+// in seen, so a collection reached again, such as a map that contains
+// itself, is converted once and keeps pointing at the same Go value.
+func convertFromLango(value interface{}, target reflect.Type, seen map[conversion]reflect.Value) (reflect.Value, error) {
+	if target.Kind() == reflect.Interface {
+		if value == nil {
+			return reflect.Zero(target), nil
+		}
+		if !reflect.TypeOf(value).Implements(target) {
+			return reflect.Value{}, fmt.Errorf("expected %s, got %s", target, typeName(value))
+		}
+		return reflect.ValueOf(value).Convert(target), nil
+	}
+
+	if value == nil {
+		switch target.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(target), nil
+		}
+		return reflect.Value{}, fmt.Errorf("expected %s, got nil", target)
+	}
+
+	if reflect.TypeOf(value) == target {
+		return reflect.ValueOf(value), nil
+	}
+
+	if object, ok := value.(*GoObject); ok {
+		if object.value.Type().AssignableTo(target) {
+			return object.value, nil
+		}
+		if object.value.Kind() == reflect.Ptr && object.value.Type().Elem().AssignableTo(target) {
+			return object.value.Elem(), nil
+		}
+		return reflect.Value{}, fmt.Errorf("expected %s, got %s", target, object.value.Type())
+	}
+
+	switch target.Kind() {
+	case reflect.Bool:
+		if b, ok := value.(bool); ok {
+			return reflect.ValueOf(b).Convert(target), nil
+		}
+		return reflect.Value{}, fmt.Errorf("expected boolean, got %s", typeName(value))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := integerValue(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		result := reflect.New(target).Elem()
+		switch target.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if !n.IsInt64() || result.OverflowInt(n.Int64()) {
+				return reflect.Value{}, fmt.Errorf("%s overflows %s", n, target)
+			}
+			result.SetInt(n.Int64())
+		default:
+			if !n.IsUint64() || result.OverflowUint(n.Uint64()) {
+				return reflect.Value{}, fmt.Errorf("%s overflows %s", n, target)
+			}
+			result.SetUint(n.Uint64())
+		}
+		return result, nil
+	case reflect.Float32, reflect.Float64:
+		if f, ok := toFloat(value); ok {
+			return reflect.ValueOf(f).Convert(target), nil
+		}
+		return reflect.Value{}, fmt.Errorf("expected number, got %s", typeName(value))
+	case reflect.String:
+		if s, ok := value.(string); ok {
+			return reflect.ValueOf(s).Convert(target), nil
+		}
+		return reflect.Value{}, fmt.Errorf("expected string, got %s", typeName(value))
+	case reflect.Ptr:
+		if target == bigIntType {
+			n, err := integerValue(value)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(new(big.Int).Set(n)), nil
+		}
+	case reflect.Slice:
+		list, ok := value.(*List)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected list, got %s", typeName(value))
+		}
+		if converted, ok := seen[conversion{list, target}]; ok {
+			return converted, nil
+		}
+		result := reflect.MakeSlice(target, len(list.Elements), len(list.Elements))
+		seen[conversion{list, target}] = result
+		for n, element := range list.Elements {
+			converted, err := convertFromLango(element, target.Elem(), seen)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %s", n, err.Error())
+			}
+			result.Index(n).Set(converted)
+		}
+		return result, nil
+	case reflect.Map:
+		m, ok := value.(*Map)
+		if !ok || target.Key().Kind() != reflect.String {
+			return reflect.Value{}, fmt.Errorf("expected map, got %s", typeName(value))
+		}
+		if converted, ok := seen[conversion{m, target}]; ok {
+			return converted, nil
+		}
+		result := reflect.MakeMapWithSize(target, len(m.Entries))
+		seen[conversion{m, target}] = result
+		for key, entry := range m.Entries {
+			converted, err := convertFromLango(entry, target.Elem(), seen)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key '%s': %s", key, err.Error())
+			}
+			result.SetMapIndex(reflect.ValueOf(key).Convert(target.Key()), converted)
+		}
+		return result, nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("unsupported Go type %s", target)
+}
+
+// isConvertibleType reports whether values of type t can be produced by
+// fromLango.
+func isConvertibleType(t reflect.Type) bool {
+	return isConvertible(t, make(map[reflect.Type]bool))
+}
+
+// isConvertible is isConvertibleType for t inside the types in checking,
+// which a recursive type such as type tree map[string]tree refers back to.
+func isConvertible(t reflect.Type, checking map[reflect.Type]bool) bool {
+	if checking[t] {
+		return true
+	}
+	checking[t] = true
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Interface,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return isConvertible(t.Elem(), checking)
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && isConvertible(t.Elem(), checking)
+	case reflect.Struct:
+		return true
+	case reflect.Ptr:
+		return t == bigIntType || t == listType || t == mapType || t.Elem().Kind() == reflect.Struct
+	}
+	return false
+}
+
+// integerValue returns the integer a Lango number holds, accepting floats
+// with no fractional part.
+func integerValue(value interface{}) (*big.Int, error) {
+	if n, ok := toBigInt(value); ok {
+		return n, nil
+	}
+	f, ok := value.(float64)
+	if !ok {
+		return nil, fmt.Errorf("expected number, got %s", typeName(value))
+	}
+	if f != math.Trunc(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("expected integer, got %v", f)
+	}
+	n, _ := big.NewFloat(f).Int(nil)
+	return n, nil
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "boolean"
+	case int64, *big.Int, float64:
+		return "number"
+	case string:
+		return "string"
+	case *List:
+		return "list"
+	case *Map:
+		return "map"
+	case *GoObject:
+		return "object"
+	case Callable:
+		return "function"
+	}
+	return fmt.Sprintf("%T", value)
+}
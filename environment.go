@@ -5,6 +5,13 @@ import "fmt"
 type Environment struct {
 	values    map[string]interface{}
 	enclosing *Environment
+	// frozen environments are never written to; assignments to their
+	// variables shadow them in the enclosed environment instead, or fail
+	// when made to the frozen environment itself.
+	frozen bool
+	// constants holds the names declared with const, which can never be
+	// assigned.
+	constants map[string]bool
 }
 
 func NewEnvironment(enclosing *Environment) *Environment {
@@ -18,6 +25,15 @@ func (e *Environment) Define(name string, value interface{}) {
 	e.values[name] = value
 }
 
+// DefineConstant defines name as a constant.
+func (e *Environment) DefineConstant(name string, value interface{}) {
+	e.values[name] = value
+	if e.constants == nil {
+		e.constants = make(map[string]bool)
+	}
+	e.constants[name] = true
+}
+
 func (e *Environment) Get(name *Token) (interface{}, error) {
 	if val, ok := e.values[name.Lexeme]; ok {
 		return val, nil
@@ -27,18 +43,31 @@ func (e *Environment) Get(name *Token) (interface{}, error) {
 		return e.enclosing.Get(name)
 	}
 
-	return nil, fmt.Errorf("undefined variable '%s' at line %d", name.Lexeme, name.Line)
+	return nil, &RuntimeError{Token: name, Message: fmt.Sprintf("Undefined variable '%s'.", name.Lexeme)}
 }
 
 func (e *Environment) Assign(name *Token, value interface{}) error {
 	if _, ok := e.values[name.Lexeme]; ok {
+		if e.constants[name.Lexeme] {
+			return &RuntimeError{Token: name, Message: fmt.Sprintf("Cannot assign to constant '%s'.", name.Lexeme)}
+		}
+		if e.frozen {
+			return &RuntimeError{Token: name, Message: fmt.Sprintf("Cannot assign to frozen variable '%s'.", name.Lexeme)}
+		}
 		e.values[name.Lexeme] = value
 		return nil
 	}
 
 	if e.enclosing != nil {
+		if _, ok := e.enclosing.values[name.Lexeme]; ok && e.enclosing.frozen {
+			if e.enclosing.constants[name.Lexeme] {
+				return &RuntimeError{Token: name, Message: fmt.Sprintf("Cannot assign to constant '%s'.", name.Lexeme)}
+			}
+			e.values[name.Lexeme] = value
+			return nil
+		}
 		return e.enclosing.Assign(name, value)
 	}
 
-	return fmt.Errorf("undefined variable '%s' at line %d", name.Lexeme, name.Line)
+	return &RuntimeError{Token: name, Message: fmt.Sprintf("Undefined variable '%s'.", name.Lexeme)}
 }
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RuntimeError is an error raised by the interpreter while running a
+// script. Scripts can catch it as an error object.
+type RuntimeError struct {
+	Token   *Token
+	Message string
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("[line %d] Runtime error at '%s': %s", e.Token.Line, e.Token.Lexeme, e.Message)
+}
+
+// ThrowError carries a value thrown by a throw statement up to the nearest
+// enclosing catch.
+type ThrowError struct {
+	Token   *Token
+	Value   interface{}
+	display string
+}
+
+func (e *ThrowError) Error() string {
+	return fmt.Sprintf("[line %d] Uncaught exception: %s", e.Token.Line, e.display)
+}
+
+// ErrorObject is the value a catch clause receives for a runtime error.
+type ErrorObject struct {
+	Message string
+	Line    int
+}
+
+func (e *ErrorObject) Get(name *Token) (interface{}, error) {
+	switch name.Lexeme {
+	case "message":
+		return e.Message, nil
+	case "line":
+		return int64(e.Line), nil
+	}
+	return nil, fmt.Errorf("Undefined property '%s' on error.", name.Lexeme)
+}
+
+func (e *ErrorObject) Set(name *Token, value interface{}) error {
+	return fmt.Errorf("Cannot assign to '%s' of an error.", name.Lexeme)
+}
+
+func (e *ErrorObject) String() string {
+	return e.Message
+}
+
+func (i *Interpreter) VisitThrowStmt(stmt *Throw) (interface{}, error) {
+	value, err := i.evaluate(stmt.Value)
+	if err != nil {
+		return nil, err
+	}
+	return nil, &ThrowError{Token: stmt.Keyword, Value: value, display: i.stringify(value)}
+}
+
+func (i *Interpreter) VisitTryStmt(stmt *Try) (interface{}, error) {
+	err := i.executeBlock(stmt.Body.Statements, NewEnvironment(i.environment))
+
+	if err != nil && stmt.CatchBody != nil {
+		if value, ok := i.catch(err); ok {
+			environment := NewEnvironment(i.environment)
+			environment.Define(stmt.CatchName.Lexeme, value)
+			err = i.executeBlock(stmt.CatchBody.Statements, environment)
+		}
+	}
+
+	if stmt.FinallyBody != nil {
+		if finallyErr := i.executeBlock(stmt.FinallyBody.Statements, NewEnvironment(i.environment)); finallyErr != nil {
+			return nil, finallyErr
+		}
+	}
+	return nil, err
+}
+
+// catch returns the value a catch clause binds for err, or false if err
+// cannot be caught by scripts, such as an exceeded limit.
+func (i *Interpreter) catch(err error) (interface{}, bool) {
+	var thrown *ThrowError
+	if errors.As(err, &thrown) {
+		return thrown.Value, true
+	}
+	var runtimeErr *RuntimeError
+	if errors.As(err, &runtimeErr) {
+		return &ErrorObject{Message: runtimeErr.Message, Line: runtimeErr.Token.Line}, true
+	}
+	return nil, false
+}
@@ -13,6 +13,18 @@ func (a *Assign) Accept(visitor Visitor) (interface{}, error) {
 	return visitor.VisitAssignExpr(a)
 }
 
+// CompoundAssign is an assignment such as x += 1. Target is a Variable
+// or a Get.
+type CompoundAssign struct {
+	Target   Expr
+	Operator *Token
+	Value    Expr
+}
+
+func (c *CompoundAssign) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCompoundAssignExpr(c)
+}
+
 type Binary struct {
 	Left     Expr
 	Operator *Token
@@ -23,6 +35,43 @@ func (b *Binary) Accept(visitor Visitor) (interface{}, error) {
 	return visitor.VisitBinaryExpr(b)
 }
 
+type Call struct {
+	Callee    Expr
+	Paren     *Token
+	Arguments []Expr
+	// Names holds the name of each argument passed as name: value, with
+	// nil for positional ones. It is nil when no argument is named.
+	Names []*Token
+	// Optional is set for f?.(), which skips the call when f is nil.
+	Optional bool
+}
+
+func (c *Call) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCallExpr(c)
+}
+
+type Conditional struct {
+	Condition Expr
+	Question  *Token
+	Then      Expr
+	Else      Expr
+}
+
+func (c *Conditional) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitConditionalExpr(c)
+}
+
+type Get struct {
+	Object Expr
+	Name   *Token
+	// Optional is set for a?.b, which skips the lookup when a is nil.
+	Optional bool
+}
+
+func (g *Get) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitGetExpr(g)
+}
+
 type Grouping struct {
 	Expression Expr
 }
@@ -33,12 +82,93 @@ func (g *Grouping) Accept(visitor Visitor) (interface{}, error) {
 
 type Literal struct {
 	Value interface{}
+	// Lexeme is the literal's source text for numbers, kept so the
+	// AstPrinter can reproduce the form it was written in.
+	Lexeme string
 }
 
 func (l *Literal) Accept(visitor Visitor) (interface{}, error) {
 	return visitor.VisitLiteralExpr(l)
 }
 
+// Lambda is a fun expression or an arrow lambda. An arrow with an
+// expression body is parsed into a single return statement.
+type Lambda struct {
+	Keyword *Token
+	// Name is set for functions declared with fun name().
+	Name   *Token
+	Params []*Parameter
+	Body   []Stmt
+}
+
+// Parameter is a function parameter. Default is evaluated when the
+// argument is omitted, and a Rest parameter collects the remaining
+// positional arguments into a list. A destructured parameter has a
+// Pattern instead of a Name.
+type Parameter struct {
+	Name    *Token
+	Pattern *Pattern
+	Default Expr
+	Rest    bool
+}
+
+func (l *Lambda) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitLambdaExpr(l)
+}
+
+// Logical is a binary expression whose right operand is only evaluated
+// when the left one does not decide the result.
+type Logical struct {
+	Left     Expr
+	Operator *Token
+	Right    Expr
+}
+
+func (l *Logical) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitLogicalExpr(l)
+}
+
+// Match is a match expression. Its value is that of the first arm whose
+// pattern matches Subject and whose guard, if any, is truthy.
+type Match struct {
+	Keyword *Token
+	Subject Expr
+	Arms    []*MatchArm
+}
+
+func (m *Match) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitMatchExpr(m)
+}
+
+// MatchArm is one arm of a match. Its body is either an expression Value
+// or a Body block, whose value is nil.
+type MatchArm struct {
+	Pattern *Pattern
+	Guard   Expr
+	Value   Expr
+	Body    *Block
+}
+
+// OptionalChain wraps a chain of gets and calls containing ?. so that a
+// nil receiver anywhere in it makes the whole chain nil.
+type OptionalChain struct {
+	Expression Expr
+}
+
+func (o *OptionalChain) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitOptionalChainExpr(o)
+}
+
+type Set struct {
+	Object Expr
+	Name   *Token
+	Value  Expr
+}
+
+func (s *Set) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitSetExpr(s)
+}
+
 type Unary struct {
 	Operator *Token
 	Right    Expr
@@ -48,6 +178,17 @@ func (u *Unary) Accept(visitor Visitor) (interface{}, error) {
 	return visitor.VisitUnaryExpr(u)
 }
 
+// Update is a prefix or postfix ++ or --.
+type Update struct {
+	Target   Expr
+	Operator *Token
+	Prefix   bool
+}
+
+func (u *Update) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitUpdateExpr(u)
+}
+
 type Variable struct {
 	Name *Token
 }
@@ -0,0 +1,152 @@
+package main
+
+import "fmt"
+
+// Function is a function value created from a fun expression or an arrow
+// lambda. It closes over the environment it was created in.
+type Function struct {
+	declaration *Lambda
+	closure     *Environment
+}
+
+func (f *Function) Arity() int {
+	for _, param := range f.declaration.Params {
+		if param.Default != nil || param.Rest {
+			return -1
+		}
+	}
+	return len(f.declaration.Params)
+}
+
+func (f *Function) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	return f.call(interpreter, arguments, nil)
+}
+
+// call runs the function with arguments, where names holds the parameter
+// name of each named argument as in a Call expression.
+func (f *Function) call(interpreter *Interpreter, arguments []interface{}, names []*Token) (interface{}, error) {
+	environment, err := f.bind(interpreter, arguments, names)
+	if err != nil {
+		return nil, err
+	}
+
+	err = interpreter.executeBlock(f.declaration.Body, environment)
+	if returned, ok := err.(*returnValue); ok {
+		return returned.value, nil
+	}
+	return nil, err
+}
+
+// bind creates the environment for a call, matching arguments to
+// parameters and evaluating the defaults of those left out.
+func (f *Function) bind(interpreter *Interpreter, arguments []interface{}, names []*Token) (*Environment, error) {
+	params := f.declaration.Params
+	values := make([]interface{}, len(params))
+	given := make([]bool, len(params))
+	rest := []interface{}{}
+
+	for n, argument := range arguments {
+		if names != nil && names[n] != nil {
+			k := f.parameter(names[n].Lexeme)
+			if k < 0 || params[k].Rest {
+				return nil, fmt.Errorf("Unexpected argument '%s'.", names[n].Lexeme)
+			}
+			if given[k] {
+				return nil, fmt.Errorf("Argument '%s' was given more than once.", names[n].Lexeme)
+			}
+			values[k], given[k] = argument, true
+			continue
+		}
+		if n < len(params) && !params[n].Rest {
+			values[n], given[n] = argument, true
+			continue
+		}
+		if len(params) > 0 && params[len(params)-1].Rest {
+			rest = append(rest, argument)
+			continue
+		}
+		return nil, fmt.Errorf("Unexpected argument %d: expected at most %d.", n+1, len(params))
+	}
+
+	environment := NewEnvironment(f.closure)
+	for k, param := range params {
+		var value interface{}
+		switch {
+		case param.Rest:
+			value = NewList(rest)
+		case given[k]:
+			value = values[k]
+		case param.Default != nil:
+			var err error
+			if value, err = interpreter.evaluateIn(param.Default, environment); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("Missing argument for parameter '%s'.", param)
+		}
+
+		if param.Pattern != nil {
+			if err := interpreter.destructure(param.Pattern, value, environment); err != nil {
+				return nil, err
+			}
+		} else {
+			environment.Define(param.Name.Lexeme, value)
+		}
+	}
+	return environment, nil
+}
+
+func (f *Function) parameter(name string) int {
+	for k, param := range f.declaration.Params {
+		if param.Name != nil && param.Name.Lexeme == name {
+			return k
+		}
+	}
+	return -1
+}
+
+func (f *Function) String() string {
+	if f.declaration.Name != nil {
+		return fmt.Sprintf("<fn %s>", f.declaration.Name.Lexeme)
+	}
+	return fmt.Sprintf("<fn line %d>", f.declaration.Keyword.Line)
+}
+
+func (p *Parameter) names() []*Token {
+	if p.Pattern != nil {
+		return p.Pattern.Names()
+	}
+	return []*Token{p.Name}
+}
+
+func (p *Parameter) String() string {
+	if p.Pattern != nil {
+		return p.Pattern.String()
+	}
+	return p.Name.Lexeme
+}
+
+// returnValue unwinds a function body from a return statement to the
+// call that is running it. It is never caught by try.
+type returnValue struct {
+	value interface{}
+}
+
+func (r *returnValue) Error() string {
+	return "return outside of a function"
+}
+
+func (i *Interpreter) VisitLambdaExpr(expr *Lambda) (interface{}, error) {
+	return &Function{declaration: expr, closure: i.environment}, nil
+}
+
+func (i *Interpreter) VisitReturnStmt(stmt *Return) (interface{}, error) {
+	var value interface{}
+	if stmt.Value != nil {
+		var err error
+		if value, err = i.evaluate(stmt.Value); err != nil {
+			return nil, err
+		}
+	}
+	return nil, &returnValue{value: value}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Object is implemented by values whose properties scripts can read and
+// write with the '.' operator.
+type Object interface {
+	Get(name *Token) (interface{}, error)
+	Set(name *Token, value interface{}) error
+}
+
+// GoObject exposes a Go struct to scripts. Exported fields are readable
+// and, when the struct was passed by pointer, writable; exported methods
+// are callable as native functions.
+type GoObject struct {
+	value reflect.Value
+}
+
+var goObjectType = reflect.TypeOf((*GoObject)(nil))
+
+func NewGoObject(value interface{}) (*GoObject, error) {
+	return newGoObject(reflect.ValueOf(value))
+}
+
+func newGoObject(value reflect.Value) (*GoObject, error) {
+	target := value
+	if target.Kind() == reflect.Ptr {
+		if target.IsNil() {
+			return nil, fmt.Errorf("cannot bind nil %s", value.Type())
+		}
+		target = target.Elem()
+	}
+	if target.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("unsupported Go type %s", value.Type())
+	}
+	return &GoObject{value: value}, nil
+}
+
+func (o *GoObject) Interface() interface{} {
+	return o.value.Interface()
+}
+
+func (o *GoObject) Get(name *Token) (interface{}, error) {
+	if method := o.value.MethodByName(name.Lexeme); method.IsValid() {
+		return wrapGoFunc(name.Lexeme, method)
+	}
+
+	field, err := o.field(name)
+	if err != nil {
+		return nil, err
+	}
+	if field.Kind() == reflect.Struct && field.CanAddr() {
+		return newGoObject(field.Addr())
+	}
+	return toLango(field)
+}
+
+func (o *GoObject) Set(name *Token, value interface{}) error {
+	field, err := o.field(name)
+	if err != nil {
+		return err
+	}
+	if !field.CanSet() {
+		return fmt.Errorf("Cannot assign to field '%s' of %s passed by value.", name.Lexeme, o.value.Type())
+	}
+	converted, err := fromLango(value, field.Type())
+	if err != nil {
+		return fmt.Errorf("Cannot assign to field '%s': %s.", name.Lexeme, err.Error())
+	}
+	field.Set(converted)
+	return nil
+}
+
+func (o *GoObject) field(name *Token) (reflect.Value, error) {
+	target := reflect.Indirect(o.value)
+	structField, ok := target.Type().FieldByName(name.Lexeme)
+	if !ok || !structField.IsExported() {
+		return reflect.Value{}, fmt.Errorf("Undefined property '%s' on %s.", name.Lexeme, o.value.Type())
+	}
+	return target.FieldByIndexErr(structField.Index)
+}
+
+func (o *GoObject) String() string {
+	return fmt.Sprintf("%v", o.value.Interface())
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+type point struct {
+	X, Y   int
+	Label  string
+	hidden int
+}
+
+func (p *point) Sum() int {
+	return p.X + p.Y
+}
+
+func TestGoObjectFields(t *testing.T) {
+	p := &point{X: 1, Y: 2}
+	interpreter := NewInterpreter(Options{})
+	if err := interpreter.Define("p", p); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, err := runScript(interpreter, `
+		p.X = p.X + 10;
+		p.Label = "moved";
+		print p.X;
+		print p.Sum();
+	`)
+	if err != nil {
+		t.Fatalf("Run() = %v, stderr %q", err, stderr)
+	}
+	if want := "11\n13\n"; stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+	if p.X != 11 || p.Label != "moved" {
+		t.Errorf("p = %+v, want the script's assignments", *p)
+	}
+}
+
+func TestGoObjectFieldErrors(t *testing.T) {
+	interpreter := NewInterpreter(Options{})
+	if err := interpreter.Define("p", &point{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := interpreter.Define("v", point{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		source string
+		stderr string
+	}{
+		{`print p.Z;`, "Undefined property 'Z' on *main.point."},
+		{`print p.hidden;`, "Undefined property 'hidden' on *main.point."},
+		{`p.hidden = 1;`, "Undefined property 'hidden' on *main.point."},
+		{`p.X = "a";`, "Cannot assign to field 'X': expected number, got string."},
+		{`v.X = 1;`, "Cannot assign to field 'X' of main.point passed by value."},
+	}
+	for _, test := range tests {
+		_, stderr, err := runScript(interpreter, test.source)
+		if err == nil || !strings.Contains(stderr, test.stderr) {
+			t.Errorf("%s: Run() = %v, stderr %q, want %q", test.source, err, stderr, test.stderr)
+		}
+	}
+}
@@ -1,42 +1,233 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"reflect"
 	"strconv"
 )
 
+// Interpreter executes Lango programs. Each Interpreter owns its globals,
+// I/O streams and limits, so separate instances may run in parallel
+// goroutines; a single instance must not be used concurrently.
 type Interpreter struct {
-	environment *Environment
+	builtins     *Environment
+	globals      *Environment
+	environment  *Environment
+	stdout       io.Writer
+	stderr       io.Writer
+	stdin        io.Reader
+	ctx          context.Context
+	limits       Limits
+	steps        int
+	callDepth    int
+	environments int
+	deferred     [][]Stmt
+
+	loader    ModuleLoader
+	file      string
+	modules   map[string]*Module
+	importing []string
+	module    *Module
+
+	hadRuntimeError bool
 }
 
-func NewInterpreter() *Interpreter {
-	return &Interpreter{
-		environment: NewEnvironment(nil),
+// Options configures a new Interpreter.
+type Options struct {
+	// Capabilities selects which groups of built-in functions are
+	// available. The zero value grants none. CapabilityFileSystem also lets
+	// scripts import modules from the OS filesystem.
+	Capabilities Capability
+	// PermissionErrors defines denied built-ins as functions that raise a
+	// permission error instead of leaving them undefined.
+	PermissionErrors bool
+}
+
+func NewInterpreter(options Options) *Interpreter {
+	builtins := NewEnvironment(nil)
+	globals := NewEnvironment(builtins)
+	interpreter := &Interpreter{
+		builtins:    builtins,
+		globals:     globals,
+		environment: globals,
+		modules:     make(map[string]*Module),
+		stdout:      os.Stdout,
+		stderr:      os.Stderr,
+		stdin:       os.Stdin,
+	}
+	if options.Capabilities&CapabilityFileSystem != 0 {
+		interpreter.loader = NewOSLoader()
+	}
+	interpreter.defineBuiltins(options)
+	return interpreter
+}
+
+// Define binds a Go value to a name visible to scripts and the modules they
+// import. Functions are wrapped as native functions whose arguments and
+// results are converted between Go and Lango values; other values are
+// converted directly.
+func (i *Interpreter) Define(name string, value interface{}) error {
+	converted, err := i.convertDefinition(name, value)
+	if err != nil {
+		return err
+	}
+	i.builtins.Define(name, converted)
+	return nil
+}
+
+// DefineConstant is like Define, but scripts can neither assign to name
+// nor modify the lists and maps inside value.
+func (i *Interpreter) DefineConstant(name string, value interface{}) error {
+	converted, err := i.convertDefinition(name, value)
+	if err != nil {
+		return err
 	}
+	i.builtins.DefineConstant(name, freeze(converted))
+	return nil
+}
+
+func (i *Interpreter) convertDefinition(name string, value interface{}) (interface{}, error) {
+	if fn := reflect.ValueOf(value); fn.Kind() == reflect.Func {
+		return wrapGoFunc(name, fn)
+	}
+	return toLango(reflect.ValueOf(value))
+}
+
+// SetOutput redirects script output (print) to stdout and runtime
+// diagnostics to stderr.
+func (i *Interpreter) SetOutput(stdout, stderr io.Writer) {
+	i.stdout = stdout
+	i.stderr = stderr
 }
 
-func (i *Interpreter) Interpret(statements []Stmt) {
+// SetInput sets the reader scripts and native functions read input from.
+func (i *Interpreter) SetInput(stdin io.Reader) {
+	i.stdin = stdin
+}
+
+func (i *Interpreter) Stdout() io.Writer {
+	return i.stdout
+}
+
+func (i *Interpreter) Stderr() io.Writer {
+	return i.stderr
+}
+
+func (i *Interpreter) Stdin() io.Reader {
+	return i.stdin
+}
+
+// Run scans, parses and interprets source. Errors are reported to the
+// interpreter's stderr and the first one is returned.
+func (i *Interpreter) Run(source string) error {
+	return i.RunContext(context.Background(), source)
+}
+
+func (i *Interpreter) RunContext(ctx context.Context, source string) error {
+	statements, err := i.parse(source)
+	if err != nil {
+		return err
+	}
+	return i.InterpretContext(ctx, statements)
+}
+
+func (i *Interpreter) parse(source string) ([]Stmt, error) {
+	scanner := NewScanner(source)
+	scanner.SetErrorOutput(i.stderr)
+	tokens := scanner.ScanTokens()
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	tokenPtrs := make([]*Token, len(tokens))
+	for n := range tokens {
+		tokenPtrs[n] = &tokens[n]
+	}
+
+	parser := NewParser(tokenPtrs)
+	parser.SetErrorOutput(i.stderr)
+	return parser.Parse()
+}
+
+// HadRuntimeError reports whether any script run by this interpreter has
+// failed with a runtime error.
+func (i *Interpreter) HadRuntimeError() bool {
+	return i.hadRuntimeError
+}
+
+func (i *Interpreter) Interpret(statements []Stmt) error {
+	return i.InterpretContext(context.Background(), statements)
+}
+
+// InterpretContext runs statements until they finish or ctx is done. Runtime
+// errors are reported and execution moves on to the next top-level
+// statement; exceeding a limit stops the script. The first error
+// encountered is returned.
+func (i *Interpreter) InterpretContext(ctx context.Context, statements []Stmt) error {
+	if i.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, i.limits.Timeout)
+		defer cancel()
+	}
+	i.ctx = ctx
+	i.steps = 0
+	defer func() { i.ctx = nil }()
+
+	var first error
+	report := func(err error) {
+		fmt.Fprintln(i.stderr, err.Error())
+		i.hadRuntimeError = true
+		if first == nil {
+			first = err
+		}
+	}
+
+	i.deferred = append(i.deferred, nil)
 	for _, statement := range statements {
 		_, err := i.execute(statement)
 		if err != nil {
-			runtimeError(err)
+			report(err)
+			var limit *LimitExceeded
+			if errors.As(err, &limit) {
+				i.deferred = i.deferred[:len(i.deferred)-1]
+				return err
+			}
 		}
 	}
+	if err := i.runDeferred(nil); err != nil {
+		report(err)
+	}
+	return first
 }
 
 func (i *Interpreter) execute(stmt Stmt) (interface{}, error) {
+	if err := i.step(); err != nil {
+		return nil, err
+	}
 	return stmt.Accept(i)
 }
 
 func (i *Interpreter) VisitBlockStmt(stmt *Block) (interface{}, error) {
-	i.executeBlock(stmt.Statements, NewEnvironment(i.environment))
-	return nil, nil
+	return nil, i.executeBlock(stmt.Statements, NewEnvironment(i.environment))
 }
 
-func (i *Interpreter) executeBlock(statements []Stmt, environment *Environment) error {
+func (i *Interpreter) executeBlock(statements []Stmt, environment *Environment) (err error) {
+	if err := i.enterScope(); err != nil {
+		return err
+	}
+	defer i.exitScope()
+
 	previous := i.environment
 	defer func() { i.environment = previous }()
 	i.environment = environment
+
+	i.deferred = append(i.deferred, nil)
+	defer func() { err = i.runDeferred(err) }()
+
 	for _, statement := range statements {
 		_, err := i.execute(statement)
 		if err != nil {
@@ -46,6 +237,35 @@ func (i *Interpreter) executeBlock(statements []Stmt, environment *Environment)
 	return nil
 }
 
+func (i *Interpreter) VisitDeferStmt(stmt *Defer) (interface{}, error) {
+	top := len(i.deferred) - 1
+	i.deferred[top] = append(i.deferred[top], stmt.Statement)
+	return nil, nil
+}
+
+// runDeferred pops the innermost scope's deferred statements and runs them
+// in reverse order. err is the error the scope is exiting with; it takes
+// precedence over any error raised by a deferred statement, unless it only
+// unwinds a return or break.
+func (i *Interpreter) runDeferred(err error) error {
+	top := len(i.deferred) - 1
+	deferred := i.deferred[top]
+	i.deferred = i.deferred[:top]
+	for n := len(deferred) - 1; n >= 0; n-- {
+		if _, deferredErr := i.execute(deferred[n]); deferredErr != nil && (err == nil || isControlFlow(err)) {
+			err = deferredErr
+		}
+	}
+	return err
+}
+
+// isControlFlow reports whether err unwinds a return or break rather than
+// reporting a failure.
+func isControlFlow(err error) bool {
+	_, ok := err.(*returnValue)
+	return ok || err == errBreak
+}
+
 func (i *Interpreter) VisitExpressionStmt(stmt *Expression) (interface{}, error) {
 	return i.evaluate(stmt.Expression)
 }
@@ -70,6 +290,9 @@ func (i *Interpreter) VisitForStmt(stmt *For) (interface{}, error) {
 		}
 
 		_, err := i.execute(stmt.Body)
+		if err == errBreak {
+			break
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -85,6 +308,40 @@ func (i *Interpreter) VisitForStmt(stmt *For) (interface{}, error) {
 	return nil, nil
 }
 
+func (i *Interpreter) VisitForInStmt(stmt *ForIn) (interface{}, error) {
+	iterable, err := i.evaluate(stmt.Iterable)
+	if err != nil {
+		return nil, err
+	}
+
+	var elements []interface{}
+	switch v := iterable.(type) {
+	case *List:
+		elements = append(elements, v.Elements...)
+	case *Map:
+		for _, key := range v.Keys() {
+			elements = append(elements, NewList([]interface{}{key, v.Entries[key]}))
+		}
+	default:
+		return nil, i.error(stmt.Keyword, fmt.Sprintf("Cannot iterate over %s.", typeName(iterable)))
+	}
+
+	for _, element := range elements {
+		environment := NewEnvironment(i.environment)
+		if err := i.destructure(stmt.Pattern, element, environment); err != nil {
+			return nil, err
+		}
+		err := i.executeBlock([]Stmt{stmt.Body}, environment)
+		if err == errBreak {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
 func (i *Interpreter) VisitIfStmt(stmt *If) (interface{}, error) {
 	cond, err := i.evaluate(stmt.Condition)
 	if err != nil {
@@ -108,7 +365,7 @@ func (i *Interpreter) VisitPrintStmt(stmt *Print) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	fmt.Println(i.stringify(value))
+	fmt.Fprintln(i.stdout, i.stringify(value))
 	return nil, nil
 }
 
@@ -120,10 +377,9 @@ func (i *Interpreter) VisitUnaryExpr(expr *Unary) (interface{}, error) {
 
 	switch expr.Operator.Type {
 	case MINUS:
-		if value, ok := right.(float64); ok {
-			return -value, nil
-		}
-		return nil, i.error(expr.Operator, "Operand must be a number.")
+		return i.negate(expr.Operator, right)
+	case TILDE:
+		return i.complement(expr.Operator, right)
 	case BANG:
 		return !i.isTruthy(right), nil
 	}
@@ -132,6 +388,43 @@ func (i *Interpreter) VisitUnaryExpr(expr *Unary) (interface{}, error) {
 }
 
 func (i *Interpreter) VisitVarStmt(stmt *Var) (interface{}, error) {
+	names := []*Token{stmt.Name}
+	if stmt.Pattern != nil {
+		names = stmt.Pattern.Names()
+	}
+	for _, name := range names {
+		if i.environment.constants[name.Lexeme] {
+			return nil, i.error(name, fmt.Sprintf("Cannot redeclare constant '%s'.", name.Lexeme))
+		}
+	}
+
+	if stmt.Pattern != nil {
+		value, err := i.evaluate(stmt.Initializer)
+		if err != nil {
+			return nil, err
+		}
+		if !stmt.Constant {
+			return nil, i.destructure(stmt.Pattern, value, i.environment)
+		}
+		bindings := NewEnvironment(nil)
+		if err := i.destructure(stmt.Pattern, value, bindings); err != nil {
+			return nil, err
+		}
+		for name, value := range bindings.values {
+			i.environment.DefineConstant(name, value)
+		}
+		return nil, nil
+	}
+
+	if stmt.Constant {
+		value, err := i.evaluate(stmt.Initializer)
+		if err != nil {
+			return nil, err
+		}
+		i.environment.DefineConstant(stmt.Name.Lexeme, value)
+		return nil, nil
+	}
+
 	if _, ok := i.environment.values[stmt.Name.Lexeme]; !ok {
 		i.environment.Define(stmt.Name.Lexeme, nil)
 	}
@@ -156,6 +449,9 @@ func (i *Interpreter) VisitWhileStmt(stmt *While) (interface{}, error) {
 			break
 		}
 		_, err = i.execute(stmt.Body)
+		if err == errBreak {
+			break
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -163,93 +459,248 @@ func (i *Interpreter) VisitWhileStmt(stmt *While) (interface{}, error) {
 	return nil, nil
 }
 
-func (i *Interpreter) VisitBinaryExpr(expr *Binary) (interface{}, error) {
-	left, err := i.evaluate(expr.Left)
-	if err != nil {
-		return nil, err
-	}
-	right, err := i.evaluate(expr.Right)
+// errBreak unwinds from a break statement to the innermost enclosing loop
+// or switch.
+var errBreak = errors.New("break outside of a loop or switch")
+
+func (i *Interpreter) VisitBreakStmt(stmt *Break) (interface{}, error) {
+	return nil, errBreak
+}
+
+func (i *Interpreter) VisitSwitchStmt(stmt *Switch) (interface{}, error) {
+	subject, err := i.evaluate(stmt.Subject)
 	if err != nil {
 		return nil, err
 	}
 
-	switch expr.Operator.Type {
-	case MINUS:
-		if l, ok := left.(float64); ok {
-			if r, ok := right.(float64); ok {
-				return l - r, nil
-			}
+	start := -1
+	for n, clause := range stmt.Cases {
+		if clause.Values == nil {
+			continue
 		}
-		return nil, i.error(expr.Operator, "Operands must be numbers.")
-	case SLASH:
-		if l, ok := left.(float64); ok {
-			if r, ok := right.(float64); ok {
-				if r == 0 {
-					return nil, i.error(expr.Operator, "Division by zero.")
-				}
-				return l / r, nil
+		for _, value := range clause.Values {
+			caseValue, err := i.evaluate(value)
+			if err != nil {
+				return nil, err
 			}
-		}
-		return nil, i.error(expr.Operator, "Operands must be numbers.")
-	case STAR:
-		if l, ok := left.(float64); ok {
-			if r, ok := right.(float64); ok {
-				return l * r, nil
+			if i.isEqual(subject, caseValue) {
+				start = n
+				break
 			}
 		}
-		return nil, i.error(expr.Operator, "Operands must be numbers.")
-	case MOD:
-		if l, ok := left.(float64); ok {
-			if r, ok := right.(float64); ok {
-				if r == 0 {
-					return nil, i.error(expr.Operator, "Modulo by zero.")
-				}
-				return float64(int(l) % int(r)), nil
-			}
+		if start >= 0 {
+			break
 		}
-		return nil, i.error(expr.Operator, "Operands of modulo must be numbers.")
-	case PLUS:
-		if l, ok := left.(float64); ok {
-			if r, ok := right.(float64); ok {
-				return l + r, nil
+	}
+	if start < 0 {
+		for n, clause := range stmt.Cases {
+			if clause.Values == nil {
+				start = n
 			}
 		}
-		return nil, i.error(expr.Operator, "Operands must be two numbers or two strings.")
-	case GREATER:
-		if l, ok := left.(float64); ok {
-			if r, ok := right.(float64); ok {
-				return l > r, nil
-			}
+		if start < 0 {
+			return nil, nil
 		}
-		return nil, i.error(expr.Operator, "Operands must be numbers.")
-	case GREATER_EQUAL:
-		if l, ok := left.(float64); ok {
-			if r, ok := right.(float64); ok {
-				return l >= r, nil
-			}
+	}
+
+	for n := start; n < len(stmt.Cases); n++ {
+		err := i.executeBlock(stmt.Cases[n].Body, NewEnvironment(i.environment))
+		if err == errBreak {
+			return nil, nil
 		}
-		return nil, i.error(expr.Operator, "Operands must be numbers.")
-	case LESS:
-		if l, ok := left.(float64); ok {
-			if r, ok := right.(float64); ok {
-				return l < r, nil
-			}
+		if err != nil {
+			return nil, err
+		}
+		if !stmt.Cases[n].Fallthrough {
+			break
+		}
+	}
+	return nil, nil
+}
+
+func (i *Interpreter) VisitBinaryExpr(expr *Binary) (interface{}, error) {
+	left, err := i.evaluate(expr.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := i.evaluate(expr.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.binary(expr.Operator, left, right)
+}
+
+func (i *Interpreter) binary(operator *Token, left, right interface{}) (interface{}, error) {
+	switch operator.Type {
+	case MINUS, SLASH, SLASH_SLASH, STAR, MOD:
+		return i.arithmetic(operator, left, right)
+	case PLUS:
+		if isNumber(left) && isNumber(right) {
+			return i.arithmetic(operator, left, right)
 		}
-		return nil, i.error(expr.Operator, "Operands must be numbers.")
-	case LESS_EQUAL:
-		if l, ok := left.(float64); ok {
-			if r, ok := right.(float64); ok {
-				return l <= r, nil
+		if l, ok := left.(string); ok {
+			if r, ok := right.(string); ok {
+				if err := i.checkAllocation(l + r); err != nil {
+					return nil, err
+				}
+				return l + r, nil
 			}
 		}
-		return nil, i.error(expr.Operator, "Operands must be numbers.")
+		return nil, i.error(operator, "Operands must be two numbers or two strings.")
+	case STAR_STAR:
+		return i.power(operator, left, right)
+	case AMPERSAND, PIPE, CARET, LESS_LESS, GREATER_GREATER:
+		return i.bitwise(operator, left, right)
+	case GREATER, GREATER_EQUAL, LESS, LESS_EQUAL:
+		return i.compare(operator, left, right)
 	case BANG_EQUAL:
 		return !i.isEqual(left, right), nil
 	case EQUAL_EQUAL:
 		return i.isEqual(left, right), nil
 	}
 
-	return nil, i.error(expr.Operator, "Unexpected binary operator.")
+	return nil, i.error(operator, "Unexpected binary operator.")
+}
+
+func (i *Interpreter) VisitCallExpr(expr *Call) (interface{}, error) {
+	callee, err := i.evaluate(expr.Callee)
+	if err != nil {
+		return nil, err
+	}
+	if callee == nil && expr.Optional {
+		return nil, errShortCircuit
+	}
+
+	arguments := make([]interface{}, 0, len(expr.Arguments))
+	for _, argument := range expr.Arguments {
+		value, err := i.evaluate(argument)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, value)
+	}
+
+	function, ok := callee.(Callable)
+	if !ok {
+		return nil, i.error(expr.Paren, "Can only call functions.")
+	}
+	// Functions defined in scripts match their arguments themselves, so
+	// they can report which parameter is missing or unexpected.
+	script, isScript := function.(*Function)
+	if expr.Names != nil && !isScript {
+		return nil, i.error(expr.Paren, "Native functions do not accept named arguments.")
+	}
+	if arity := function.Arity(); !isScript && arity >= 0 && len(arguments) != arity {
+		return nil, i.error(expr.Paren, fmt.Sprintf("Expected %d arguments but got %d.", arity, len(arguments)))
+	}
+
+	if err := i.enterCall(expr.Paren); err != nil {
+		return nil, err
+	}
+	defer i.exitCall()
+
+	var result interface{}
+	if isScript {
+		result, err = script.call(i, arguments, expr.Names)
+	} else {
+		result, err = function.Call(i, arguments)
+	}
+	if err != nil {
+		var limit *LimitExceeded
+		var runtimeErr *RuntimeError
+		var thrown *ThrowError
+		if errors.As(err, &limit) || errors.As(err, &runtimeErr) || errors.As(err, &thrown) {
+			return nil, err
+		}
+		return nil, i.error(expr.Paren, err.Error())
+	}
+	if err := i.checkAllocation(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (i *Interpreter) VisitGetExpr(expr *Get) (interface{}, error) {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+	if object == nil && expr.Optional {
+		return nil, errShortCircuit
+	}
+
+	if obj, ok := object.(Object); ok {
+		value, err := obj.Get(expr.Name)
+		if err != nil {
+			return nil, i.error(expr.Name, err.Error())
+		}
+		return value, nil
+	}
+	return nil, i.error(expr.Name, "Only objects have properties.")
+}
+
+// errShortCircuit unwinds an optional chain from the ?. that found nil
+// to the enclosing OptionalChain.
+var errShortCircuit = errors.New("optional chain short-circuited")
+
+func (i *Interpreter) VisitOptionalChainExpr(expr *OptionalChain) (interface{}, error) {
+	value, err := i.evaluate(expr.Expression)
+	if err == errShortCircuit {
+		return nil, nil
+	}
+	return value, err
+}
+
+func (i *Interpreter) VisitSetExpr(expr *Set) (interface{}, error) {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, ok := object.(Object)
+	if !ok {
+		return nil, i.error(expr.Name, "Only objects have fields.")
+	}
+
+	value, err := i.evaluate(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := obj.Set(expr.Name, value); err != nil {
+		return nil, i.error(expr.Name, err.Error())
+	}
+	if err := i.checkAllocation(obj); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (i *Interpreter) VisitConditionalExpr(expr *Conditional) (interface{}, error) {
+	condition, err := i.evaluate(expr.Condition)
+	if err != nil {
+		return nil, err
+	}
+	if i.isTruthy(condition) {
+		return i.evaluate(expr.Then)
+	}
+	return i.evaluate(expr.Else)
+}
+
+func (i *Interpreter) VisitLogicalExpr(expr *Logical) (interface{}, error) {
+	left, err := i.evaluate(expr.Left)
+	if err != nil {
+		return nil, err
+	}
+
+	switch expr.Operator.Type {
+	case QUESTION_QUESTION:
+		if left != nil {
+			return left, nil
+		}
+		return i.evaluate(expr.Right)
+	}
+	return nil, i.error(expr.Operator, "Unexpected logical operator.")
 }
 
 func (i *Interpreter) VisitGroupingExpr(expr *Grouping) (interface{}, error) {
@@ -269,10 +720,108 @@ func (i *Interpreter) VisitAssignExpr(expr *Assign) (interface{}, error) {
 	return value, nil
 }
 
+func (i *Interpreter) VisitCompoundAssignExpr(expr *CompoundAssign) (interface{}, error) {
+	operator := *expr.Operator
+	switch operator.Type {
+	case PLUS_EQUAL:
+		operator.Type = PLUS
+	case MINUS_EQUAL:
+		operator.Type = MINUS
+	case STAR_EQUAL:
+		operator.Type = STAR
+	case SLASH_EQUAL:
+		operator.Type = SLASH
+	case MOD_EQUAL:
+		operator.Type = MOD
+	}
+
+	_, value, err := i.update(expr.Target, func(current interface{}) (interface{}, error) {
+		right, err := i.evaluate(expr.Value)
+		if err != nil {
+			return nil, err
+		}
+		return i.binary(&operator, current, right)
+	})
+	return value, err
+}
+
+func (i *Interpreter) VisitUpdateExpr(expr *Update) (interface{}, error) {
+	operator := *expr.Operator
+	operator.Type = PLUS
+	if expr.Operator.Type == MINUS_MINUS {
+		operator.Type = MINUS
+	}
+
+	previous, value, err := i.update(expr.Target, func(current interface{}) (interface{}, error) {
+		if !isNumber(current) {
+			return nil, i.error(expr.Operator, "Operand must be a number.")
+		}
+		return i.arithmetic(&operator, current, int64(1))
+	})
+	if expr.Prefix {
+		return value, err
+	}
+	return previous, err
+}
+
+// update reads the variable or field target, stores compute's result back
+// into it and returns both values. Any object expression in target is
+// evaluated only once.
+func (i *Interpreter) update(target Expr, compute func(interface{}) (interface{}, error)) (interface{}, interface{}, error) {
+	switch target := target.(type) {
+	case *Variable:
+		current, err := i.environment.Get(target.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		value, err := compute(current)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := i.environment.Assign(target.Name, value); err != nil {
+			return nil, nil, err
+		}
+		return current, value, nil
+	case *Get:
+		object, err := i.evaluate(target.Object)
+		if err != nil {
+			return nil, nil, err
+		}
+		obj, ok := object.(Object)
+		if !ok {
+			return nil, nil, i.error(target.Name, "Only objects have fields.")
+		}
+		current, err := obj.Get(target.Name)
+		if err != nil {
+			return nil, nil, i.error(target.Name, err.Error())
+		}
+		value, err := compute(current)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := obj.Set(target.Name, value); err != nil {
+			return nil, nil, i.error(target.Name, err.Error())
+		}
+		if err := i.checkAllocation(obj); err != nil {
+			return nil, nil, err
+		}
+		return current, value, nil
+	}
+	return nil, nil, fmt.Errorf("Invalid assignment target.")
+}
+
 func (i *Interpreter) evaluate(expr Expr) (interface{}, error) {
 	return expr.Accept(i)
 }
 
+// evaluateIn evaluates expr with environment as the current scope.
+func (i *Interpreter) evaluateIn(expr Expr, environment *Environment) (interface{}, error) {
+	previous := i.environment
+	defer func() { i.environment = previous }()
+	i.environment = environment
+	return i.evaluate(expr)
+}
+
 func (i *Interpreter) isTruthy(object interface{}) bool {
 	if object == nil {
 		return false
@@ -284,12 +833,19 @@ func (i *Interpreter) isTruthy(object interface{}) bool {
 }
 
 func (i *Interpreter) isEqual(a, b interface{}) bool {
+	return valuesEqual(a, b)
+}
+
+func valuesEqual(a, b interface{}) bool {
 	if a == nil && b == nil {
 		return true
 	}
 	if a == nil {
 		return false
 	}
+	if isNumber(a) && isNumber(b) {
+		return !isNaN(a) && !isNaN(b) && compareNumbers(a, b) == 0
+	}
 	return a == b
 }
 
@@ -300,9 +856,18 @@ func (i *Interpreter) stringify(object interface{}) string {
 	if f, ok := object.(float64); ok {
 		return strconv.FormatFloat(f, 'f', -1, 64)
 	}
+	if n, ok := object.(int64); ok {
+		return strconv.FormatInt(n, 10)
+	}
+	if list, ok := object.(*List); ok {
+		return i.stringifyList(list, make(map[interface{}]bool))
+	}
+	if m, ok := object.(*Map); ok {
+		return i.stringifyMap(m, make(map[interface{}]bool))
+	}
 	return fmt.Sprintf("%v", object)
 }
 
 func (i *Interpreter) error(token *Token, message string) error {
-	return fmt.Errorf("Runtime error at '%s': %s", token.Lexeme, message)
+	return &RuntimeError{Token: token, Message: message}
 }
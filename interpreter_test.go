@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// runScript runs source in interpreter and returns what it printed to
+// stdout and stderr along with the error Run returned.
+func runScript(interpreter *Interpreter, source string) (string, string, error) {
+	return runScriptContext(context.Background(), interpreter, source)
+}
+
+func runScriptContext(ctx context.Context, interpreter *Interpreter, source string) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+	interpreter.SetOutput(&stdout, &stderr)
+	err := interpreter.RunContext(ctx, source)
+	return stdout.String(), stderr.String(), err
+}
+
+func TestInterpret(t *testing.T) {
+	stdout, stderr, err := runScript(NewInterpreter(Options{}), `
+		var total = 0;
+		for (var n = 1; n <= 10; n = n + 1) total = total + n;
+		print total;
+	`)
+	if err != nil || stderr != "" {
+		t.Fatalf("Run() = %v, stderr %q", err, stderr)
+	}
+	if stdout != "55\n" {
+		t.Errorf("stdout = %q, want %q", stdout, "55\n")
+	}
+}
+
+func TestRuntimeErrorContinues(t *testing.T) {
+	interpreter := NewInterpreter(Options{})
+	stdout, stderr, err := runScript(interpreter, `print missing; print "after";`)
+	if err == nil || !interpreter.HadRuntimeError() {
+		t.Fatalf("Run() = %v, want a runtime error", err)
+	}
+	if stdout != "after\n" {
+		t.Errorf("stdout = %q, want %q", stdout, "after\n")
+	}
+	if want := "Undefined variable 'missing'."; !strings.Contains(stderr, want) {
+		t.Errorf("stderr = %q, want it to contain %q", stderr, want)
+	}
+}
+
+// TestConcurrentInterpreters runs many interpreters in parallel. Run it
+// with -race to check that they share no state.
+func TestConcurrentInterpreters(t *testing.T) {
+	const scripts = 256
+
+	var wg sync.WaitGroup
+	errs := make(chan error, scripts)
+	for n := 0; n < scripts; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			interpreter := NewInterpreter(Options{})
+			interpreter.SetLimits(Limits{MaxSteps: 100000, MaxCallDepth: 64})
+			if err := interpreter.Define("id", n); err != nil {
+				errs <- err
+				return
+			}
+			stdout, stderr, err := runScript(interpreter, `
+				var total = 0;
+				var add = fun (x) { total += x; };
+				for (var i = 0; i < 100; i++) add(id);
+				print total;
+				print missing;
+			`)
+			if err == nil {
+				errs <- fmt.Errorf("script %d: expected an undefined variable error", n)
+				return
+			}
+			if want := fmt.Sprintf("%d\n", 100*n); stdout != want {
+				errs <- fmt.Errorf("script %d: stdout = %q, want %q (stderr %q)", n, stdout, want, stderr)
+			}
+		}(n)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestDeferredErrorReplacesReturn(t *testing.T) {
+	tests := []struct {
+		source string
+		stdout string
+		stderr string
+	}{
+		{`fun f() { defer print "deferred"; return 1; } print f();`, "deferred\n1\n", ""},
+		{`fun f() { defer print 1 / 0; return 1; } print f();`, "", "Division by zero."},
+		{`fun f() { defer throw "x"; return 1; } try { f(); } catch (e) { print e; }`, "x\n", ""},
+		{`while (true) { defer throw "y"; break; }`, "", "Uncaught exception: y"},
+		{`fun f() { defer throw "first"; throw "second"; } f();`, "", "Uncaught exception: second"},
+	}
+	for _, test := range tests {
+		stdout, stderr, _ := runScript(NewInterpreter(Options{}), test.source)
+		if stdout != test.stdout {
+			t.Errorf("%s: stdout = %q, want %q", test.source, stdout, test.stdout)
+		}
+		if test.stderr == "" && stderr != "" || !strings.Contains(stderr, test.stderr) {
+			t.Errorf("%s: stderr = %q, want %q", test.source, stderr, test.stderr)
+		}
+	}
+}
+
+func TestDeferCannotReturnOrBreak(t *testing.T) {
+	tests := []struct {
+		source string
+		stderr string
+	}{
+		{`fun f() { defer { return 5; } return 1; }`, "Can't return from a deferred statement."},
+		{`fun f() { defer if (true) return 5; }`, "Can't return from a deferred statement."},
+		{`while (true) { defer { break; } }`, "Can't break out of a deferred statement."},
+		{`fun f() { defer return 1; }`, "Cannot defer a 'return' statement."},
+	}
+	for _, test := range tests {
+		_, stderr, err := runScript(NewInterpreter(Options{}), test.source)
+		if err == nil || !strings.Contains(stderr, test.stderr) {
+			t.Errorf("%s: Run() = %v, stderr %q, want %q", test.source, err, stderr, test.stderr)
+		}
+	}
+
+	stdout, stderr, err := runScript(NewInterpreter(Options{}), `
+		fun f() {
+			defer {
+				while (true) break;
+				var g = fun () { return "inner"; };
+				print g();
+			}
+			return 1;
+		}
+		print f();
+	`)
+	if err != nil || stdout != "inner\n1\n" {
+		t.Errorf("Run() = %v, stdout %q, stderr %q", err, stdout, stderr)
+	}
+}
+
+func TestConditionalAndCoalescePrecedence(t *testing.T) {
+	tests := []struct {
+		source string
+		stdout string
+	}{
+		{`print 1 ?? nil ? "yes" : "no";`, "yes\n"},
+		{`print nil ?? false ? "yes" : "no";`, "no\n"},
+		{`print nil ?? 0 ? "yes" : "no";`, "yes\n"},
+		{`print true ? 1 : true ? 2 : 3;`, "1\n"},
+		{`print false ? 1 : true ? 2 : 3;`, "2\n"},
+		{`print false ? 1 : false ? 2 : 3;`, "3\n"},
+		{`print true ? false ? 1 : 2 : 3;`, "2\n"},
+		{`print false ?? 1;`, "false\n"},
+		{`print 0 ?? 1;`, "0\n"},
+		{`print 0 ?? missing;`, "0\n"},
+		{`print nil ?? nil ?? 2;`, "2\n"},
+		{`print nil ?? 1 == 1;`, "true\n"},
+	}
+	for _, test := range tests {
+		stdout, stderr, err := runScript(NewInterpreter(Options{}), test.source)
+		if err != nil || stdout != test.stdout {
+			t.Errorf("%s: Run() = %v, stdout %q, want %q (stderr %q)", test.source, err, stdout, test.stdout, stderr)
+		}
+	}
+}
+
+func TestOptionalChaining(t *testing.T) {
+	tests := []struct {
+		source string
+		stdout string
+		stderr string
+	}{
+		{`var a = nil; print a?.b.c;`, "nil\n", ""},
+		{`var a = nil; print a?.b.c.d();`, "nil\n", ""},
+		{`var a = nil; print a?.b(bump()); print calls;`, "nil\n0\n", ""},
+		{`var f = nil; print f?.(bump()).x; print calls;`, "nil\n0\n", ""},
+		{`print config?.name;`, "lango\n", ""},
+		{`print config.missing?.deep.deeper;`, "nil\n", ""},
+		{`print config.missing.deep;`, "", "Only objects have properties."},
+		{`var s = "x"; print s?.b.c;`, "", "Only objects have properties."},
+		{`var n = 1; n?.(bump()); print calls;`, "1\n", "Can only call functions."},
+	}
+	for _, test := range tests {
+		interpreter := NewInterpreter(Options{})
+		if err := interpreter.Define("config", map[string]interface{}{"name": "lango"}); err != nil {
+			t.Fatal(err)
+		}
+		source := `var calls = 0; var bump = fun () { calls++; return calls; }; ` + test.source
+		stdout, stderr, _ := runScript(interpreter, source)
+		if stdout != test.stdout {
+			t.Errorf("%s: stdout = %q, want %q", test.source, stdout, test.stdout)
+		}
+		if test.stderr == "" && stderr != "" || !strings.Contains(stderr, test.stderr) {
+			t.Errorf("%s: stderr = %q, want %q", test.source, stderr, test.stderr)
+		}
+	}
+}
+
+func TestArgumentBinding(t *testing.T) {
+	tests := []struct {
+		source string
+		stdout string
+		stderr string
+	}{
+		{`fun f(a, b = a * 2) { print b; } f(3);`, "6\n", ""},
+		{`fun f(a, b = a * 2) { print b; } f(3, 4);`, "4\n", ""},
+		{`fun f(a, b = a * 2) { print b; } f(b: 5, a: 1);`, "5\n", ""},
+		{`fun f(a, b = a * 2) { print b; } f(b: 5);`, "", "Missing argument for parameter 'a'."},
+		{`fun f(a, ...rest) { print rest; } f(1);`, "[]\n", ""},
+		{`fun f(...rest) { print rest; } f();`, "[]\n", ""},
+		{`fun f(a, ...rest) { print rest; } f(1, 2, 3);`, "[2, 3]\n", ""},
+		{`fun f(a) {} f(c: 1);`, "", "Unexpected argument 'c'."},
+		{`fun f(...rest) {} f(rest: 1);`, "", "Unexpected argument 'rest'."},
+		{`fun f(a) {} f(a: 1, a: 2);`, "", "Error at 'a': Duplicate argument 'a'."},
+		{`fun f(a, b) {} f(1, a: 2);`, "", "Argument 'a' was given more than once."},
+		{`fun f(a, b) {} f(1);`, "", "Missing argument for parameter 'b'."},
+		{`fun f(a, b) {} f(1, 2, 3);`, "", "Unexpected argument 3: expected at most 2."},
+		{`freeze(value: 1);`, "", "Native functions do not accept named arguments."},
+		{`freeze();`, "", "Expected 1 arguments but got 0."},
+	}
+	for _, test := range tests {
+		stdout, stderr, _ := runScript(NewInterpreter(Options{}), test.source)
+		if stdout != test.stdout {
+			t.Errorf("%s: stdout = %q, want %q", test.source, stdout, test.stdout)
+		}
+		if test.stderr == "" && stderr != "" || !strings.Contains(stderr, test.stderr) {
+			t.Errorf("%s: stderr = %q, want %q", test.source, stderr, test.stderr)
+		}
+	}
+}
+
+func TestSwitch(t *testing.T) {
+	tests := []struct {
+		source string
+		stdout string
+	}{
+		{`switch (2) { case 1: print "one"; case 2, 3: print "two or three"; default: print "other"; }`, "two or three\n"},
+		{`switch (9) { case 1: print "one"; default: print "other"; }`, "other\n"},
+		{`switch (9) { case 1: print "one"; case 2: print "two"; } print "after";`, "after\n"},
+		{`switch ("fri") { case "fri": print "fri"; fallthrough; case "sat": print "sat"; default: print "other"; }`, "fri\nsat\n"},
+		{`switch (1) { case 1: print "before"; break; print "skipped"; } print "after";`, "before\nafter\n"},
+		{`
+			for (var i = 0; i < 3; i++) {
+				switch (i) {
+					case 1: break;
+					default: print i;
+				}
+			}
+		`, "0\n2\n"},
+		{`
+			var i = 0;
+			while (true) {
+				switch (i) { case 2: print "two"; }
+				if (i == 3) break;
+				i++;
+			}
+			print i;
+		`, "two\n3\n"},
+	}
+	for _, test := range tests {
+		stdout, stderr, err := runScript(NewInterpreter(Options{}), test.source)
+		if err != nil || stdout != test.stdout {
+			t.Errorf("%s: Run() = %v, stdout %q, want %q (stderr %q)", test.source, err, stdout, test.stdout, stderr)
+		}
+	}
+}
+
+func TestSwitchDuplicateCase(t *testing.T) {
+	tests := []string{
+		`switch (1) { case 1: print "a"; case 1: print "b"; }`,
+		`switch ("x") { case "x", "y": print "a"; case "y": print "b"; }`,
+	}
+	for _, source := range tests {
+		stdout, stderr, err := runScript(NewInterpreter(Options{}), source)
+		if _, ok := err.(*ParseError); !ok || stdout != "" || !strings.Contains(stderr, "Duplicate case value in switch.") {
+			t.Errorf("%s: Run() = %v, stdout %q, stderr %q", source, err, stdout, stderr)
+		}
+	}
+}
+
+func TestConstantDeclarations(t *testing.T) {
+	tests := []struct {
+		source string
+		stdout string
+		stderr string
+	}{
+		{`const x = 1; let y = 2; print x + y;`, "3\n", ""},
+		{`let [a, b] = pair; print a * b;`, "6\n", ""},
+		{`const x = 1; x = 2;`, "", "Cannot assign to constant 'x'."},
+		{`let x = 1; x += 2;`, "", "Cannot assign to constant 'x'."},
+		{`let [a, b] = pair; b++;`, "", "Cannot assign to constant 'b'."},
+		{`let x;`, "", "Constant 'x' must be initialized."},
+		{`limit = 4;`, "", "Cannot assign to constant 'limit'."},
+		{`let limit = 4; print limit;`, "4\n", ""},
+	}
+	for _, test := range tests {
+		interpreter := NewInterpreter(Options{})
+		if err := interpreter.Define("pair", []int{2, 3}); err != nil {
+			t.Fatal(err)
+		}
+		if err := interpreter.DefineConstant("limit", 3); err != nil {
+			t.Fatal(err)
+		}
+		stdout, stderr, _ := runScript(interpreter, test.source)
+		if stdout != test.stdout {
+			t.Errorf("%s: stdout = %q, want %q", test.source, stdout, test.stdout)
+		}
+		if test.stderr == "" && stderr != "" || !strings.Contains(stderr, test.stderr) {
+			t.Errorf("%s: stderr = %q, want %q", test.source, stderr, test.stderr)
+		}
+	}
+}
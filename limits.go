@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Limits bounds the resources a script may consume. Zero values mean no
+// limit.
+type Limits struct {
+	// MaxSteps is the maximum number of statements executed per call to
+	// Interpret, counting every loop iteration.
+	MaxSteps int
+	// MaxCallDepth is the maximum number of nested function calls. When it
+	// is zero, calls nested more than 10000 deep raise a "Stack overflow."
+	// runtime error, which scripts can catch.
+	MaxCallDepth int
+	// Timeout is the wall-clock time allowed per call to Interpret.
+	Timeout time.Duration
+	// MaxStringLength is the maximum length in bytes of a string built by
+	// a script or returned to it by a native function.
+	MaxStringLength int
+	// MaxCollectionSize is the maximum number of elements in a list or
+	// entries in a map.
+	MaxCollectionSize int
+	// MaxEnvironments is the maximum number of scopes alive at once.
+	MaxEnvironments int
+}
+
+// LimitExceeded is returned when a script runs past one of its Limits or
+// its context is cancelled. Scripts cannot recover from it.
+type LimitExceeded struct {
+	Limit string
+	Cause error
+}
+
+func (e *LimitExceeded) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("Execution limit exceeded: %s (%s).", e.Limit, e.Cause.Error())
+	}
+	return fmt.Sprintf("Execution limit exceeded: %s.", e.Limit)
+}
+
+func (e *LimitExceeded) Unwrap() error {
+	return e.Cause
+}
+
+func (i *Interpreter) SetLimits(limits Limits) {
+	i.limits = limits
+}
+
+// Context returns the context of the script currently being interpreted so
+// that long-running native functions can honour cancellation.
+func (i *Interpreter) Context() context.Context {
+	if i.ctx == nil {
+		return context.Background()
+	}
+	return i.ctx
+}
+
+// step accounts for one executed statement and reports whether the script
+// may continue.
+func (i *Interpreter) step() error {
+	i.steps++
+	if i.limits.MaxSteps > 0 && i.steps > i.limits.MaxSteps {
+		return &LimitExceeded{Limit: fmt.Sprintf("more than %d steps", i.limits.MaxSteps)}
+	}
+	if i.ctx != nil {
+		select {
+		case <-i.ctx.Done():
+			limit := "cancelled"
+			if errors.Is(i.ctx.Err(), context.DeadlineExceeded) {
+				limit = "timeout"
+			}
+			return &LimitExceeded{Limit: limit, Cause: i.ctx.Err()}
+		default:
+		}
+	}
+	return nil
+}
+
+// defaultMaxCallDepth bounds nested calls when no MaxCallDepth is set, well
+// before deep recursion would overflow the Go stack and crash the host.
+const defaultMaxCallDepth = 10000
+
+func (i *Interpreter) enterCall(paren *Token) error {
+	if i.limits.MaxCallDepth > 0 {
+		if i.callDepth >= i.limits.MaxCallDepth {
+			return &LimitExceeded{Limit: fmt.Sprintf("call depth over %d", i.limits.MaxCallDepth)}
+		}
+	} else if i.callDepth >= defaultMaxCallDepth {
+		return i.error(paren, "Stack overflow.")
+	}
+	i.callDepth++
+	return nil
+}
+
+func (i *Interpreter) exitCall() {
+	i.callDepth--
+}
+
+// checkAllocation reports whether a value produced by the script stays
+// within the memory quotas.
+func (i *Interpreter) checkAllocation(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		if i.limits.MaxStringLength > 0 && len(v) > i.limits.MaxStringLength {
+			return &LimitExceeded{Limit: fmt.Sprintf("string length over %d", i.limits.MaxStringLength)}
+		}
+	case *List:
+		return i.checkCollectionSize(len(v.Elements))
+	case *Map:
+		return i.checkCollectionSize(len(v.Entries))
+	}
+	return nil
+}
+
+func (i *Interpreter) checkCollectionSize(size int) error {
+	if i.limits.MaxCollectionSize > 0 && size > i.limits.MaxCollectionSize {
+		return &LimitExceeded{Limit: fmt.Sprintf("collection size over %d", i.limits.MaxCollectionSize)}
+	}
+	return nil
+}
+
+func (i *Interpreter) enterScope() error {
+	if i.limits.MaxEnvironments > 0 && i.environments >= i.limits.MaxEnvironments {
+		return &LimitExceeded{Limit: fmt.Sprintf("more than %d environments", i.limits.MaxEnvironments)}
+	}
+	i.environments++
+	return nil
+}
+
+func (i *Interpreter) exitScope() {
+	i.environments--
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runLimited runs source with limits and returns the *LimitExceeded it
+// stopped with, failing the test if it did not exceed a limit.
+func runLimited(t *testing.T, limits Limits, source string) *LimitExceeded {
+	t.Helper()
+	interpreter := NewInterpreter(Options{})
+	interpreter.SetLimits(limits)
+	_, _, err := runScript(interpreter, source)
+	var limit *LimitExceeded
+	if !errors.As(err, &limit) {
+		t.Fatalf("Interpret() = %v, want a *LimitExceeded", err)
+	}
+	return limit
+}
+
+func TestMaxSteps(t *testing.T) {
+	limit := runLimited(t, Limits{MaxSteps: 1000}, `while (true) {}`)
+	if !strings.Contains(limit.Limit, "1000 steps") {
+		t.Errorf("Limit = %q", limit.Limit)
+	}
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	limit := runLimited(t, Limits{MaxCallDepth: 50}, `var f = fun (n) { return f(n + 1); }; f(0);`)
+	if !strings.Contains(limit.Limit, "call depth over 50") {
+		t.Errorf("Limit = %q", limit.Limit)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	limit := runLimited(t, Limits{Timeout: 20 * time.Millisecond}, `while (true) {}`)
+	if !errors.Is(limit, context.DeadlineExceeded) {
+		t.Errorf("Cause = %v, want %v", limit.Cause, context.DeadlineExceeded)
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	_, _, err := runScriptContext(ctx, NewInterpreter(Options{}), `while (true) {}`)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("InterpretContext() = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWithinLimits(t *testing.T) {
+	interpreter := NewInterpreter(Options{})
+	interpreter.SetLimits(Limits{MaxSteps: 1000, MaxCallDepth: 8, Timeout: time.Second})
+	stdout, stderr, err := runScript(interpreter, `
+		var n = 0;
+		while (n < 100) n = n + 1;
+		print n;
+	`)
+	if err != nil {
+		t.Fatalf("Interpret() = %v, stderr %q", err, stderr)
+	}
+	if stdout != "100\n" {
+		t.Errorf("stdout = %q, want %q", stdout, "100\n")
+	}
+}
+
+func TestMaxStringLength(t *testing.T) {
+	runLimited(t, Limits{MaxStringLength: 1024}, `var s = "ab"; while (true) s = s + s;`)
+}
+
+func TestMaxCollectionSize(t *testing.T) {
+	interpreter := NewInterpreter(Options{})
+	interpreter.SetLimits(Limits{MaxCollectionSize: 10})
+	if err := interpreter.Define("numbers", func(n int) []int { return make([]int, n) }); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, err := runScript(interpreter, `var xs = numbers(10); var ys = numbers(11);`)
+	var limit *LimitExceeded
+	if !errors.As(err, &limit) || !strings.Contains(limit.Limit, "collection size over 10") {
+		t.Errorf("Interpret() = %v, stderr %q, want a collection size limit", err, stderr)
+	}
+}
+
+func TestMaxEnvironments(t *testing.T) {
+	source := strings.Repeat("{", 40) + strings.Repeat("}", 40)
+	runLimited(t, Limits{MaxEnvironments: 32}, source)
+}
+
+func TestLimitsCannotBeCaught(t *testing.T) {
+	interpreter := NewInterpreter(Options{})
+	interpreter.SetLimits(Limits{MaxSteps: 1000})
+	stdout, _, err := runScript(interpreter, `
+		try { while (true) {} } catch (e) { print "caught"; } finally { print "finally"; }
+		print "after";
+	`)
+	var limit *LimitExceeded
+	if !errors.As(err, &limit) {
+		t.Fatalf("Interpret() = %v, want a *LimitExceeded", err)
+	}
+	if stdout != "" {
+		t.Errorf("stdout = %q, want nothing", stdout)
+	}
+}
+func TestDefaultCallDepth(t *testing.T) {
+	interpreter := NewInterpreter(Options{})
+	stdout, stderr, err := runScript(interpreter, `
+		fun f(n) { return f(n + 1); }
+		try { f(0); } catch (e) { print e.message; }
+		f(0);
+	`)
+	if stdout != "Stack overflow.\n" {
+		t.Errorf("stdout = %q, want %q", stdout, "Stack overflow.\n")
+	}
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || !strings.Contains(stderr, "Stack overflow.") {
+		t.Errorf("Run() = %v, stderr %q, want a stack overflow runtime error", err, stderr)
+	}
+
+	stdout, stderr, _ = runScript(interpreter, `
+		fun count(n) { if (n == 0) return 0; return count(n - 1) + 1; }
+		print count(5000);
+	`)
+	if stdout != "5000\n" {
+		t.Errorf("stdout = %q, stderr %q, want recursion below the default depth to work", stdout, stderr)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ModuleLoader locates and reads the source of imported modules.
+type ModuleLoader interface {
+	// Resolve returns the canonical path of the module imported as name
+	// from the file at from, which is empty for code not read from a file.
+	Resolve(from, name string) (string, error)
+	// Load reads the module at a path returned by Resolve.
+	Load(path string) ([]byte, error)
+}
+
+// OSLoader loads modules from the operating system's filesystem, looking
+// next to the importing file first and then in each search directory.
+type OSLoader struct {
+	searchPath []string
+}
+
+func NewOSLoader(searchPath ...string) *OSLoader {
+	return &OSLoader{searchPath: searchPath}
+}
+
+func (l *OSLoader) Resolve(from, name string) (string, error) {
+	var candidates []string
+	if filepath.IsAbs(name) {
+		candidates = []string{name}
+	} else {
+		candidates = append(candidates, filepath.Join(filepath.Dir(from), name))
+		for _, dir := range l.searchPath {
+			candidates = append(candidates, filepath.Join(dir, name))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return filepath.Abs(candidate)
+		}
+	}
+	return "", fmt.Errorf("Cannot find module '%s'.", name)
+}
+
+func (l *OSLoader) Load(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+// FSLoader loads modules from an fs.FS such as an embed.FS. Paths are
+// slash-separated and a leading '/' refers to the root of the filesystem.
+type FSLoader struct {
+	fsys       fs.FS
+	searchPath []string
+}
+
+func NewFSLoader(fsys fs.FS, searchPath ...string) *FSLoader {
+	return &FSLoader{fsys: fsys, searchPath: searchPath}
+}
+
+func (l *FSLoader) Resolve(from, name string) (string, error) {
+	var candidates []string
+	if strings.HasPrefix(name, "/") {
+		candidates = []string{path.Clean(strings.TrimPrefix(name, "/"))}
+	} else {
+		candidates = append(candidates, path.Join(path.Dir(from), name))
+		for _, dir := range l.searchPath {
+			candidates = append(candidates, path.Join(dir, name))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if !fs.ValidPath(candidate) {
+			continue
+		}
+		if info, err := fs.Stat(l.fsys, candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("Cannot find module '%s'.", name)
+}
+
+func (l *FSLoader) Load(path string) ([]byte, error) {
+	return fs.ReadFile(l.fsys, path)
+}
@@ -2,15 +2,14 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
-var hadRuntimeError bool
-var interpreter = NewInterpreter()
-
 func main() {
 	if len(os.Args) > 2 {
 		fmt.Println("Usage: Lango [script.lango]")
@@ -28,15 +27,25 @@ func main() {
 }
 
 func runFile(path string) {
-	bytes, err := os.ReadFile(path)
-	if err != nil {
+	interpreter := NewInterpreter(Options{Capabilities: AllCapabilities})
+	interpreter.SetModuleLoader(NewOSLoader(filepath.SplitList(os.Getenv("LANGO_PATH"))...))
+	err := interpreter.RunFile(path)
+	var pathErr *fs.PathError
+	var parseErr *ParseError
+	if errors.As(err, &pathErr) {
 		fmt.Println("Error reading file:", err)
 		os.Exit(1)
+	} else if errors.As(err, &parseErr) {
+		fmt.Println("Error during parsing:", err)
+	}
+	if interpreter.HadRuntimeError() {
+		os.Exit(70)
 	}
-	run(string(bytes))
 }
 
 func runPrompt() {
+	interpreter := NewInterpreter(Options{Capabilities: AllCapabilities})
+	interpreter.SetModuleLoader(NewOSLoader(filepath.SplitList(os.Getenv("LANGO_PATH"))...))
 	reader := bufio.NewReader(os.Stdin)
 	for {
 		fmt.Print("> ")
@@ -49,27 +58,16 @@ func runPrompt() {
 			fmt.Println("Exiting...")
 			break
 		}
-		run(line)
+		run(interpreter, line)
 	}
 }
 
-func run(source string) {
-	scanner := NewScanner(source)
-	tokens := scanner.ScanTokens()
-
-	tokenPtrs := make([]*Token, len(tokens))
-	for i := range tokens {
-		tokenPtrs[i] = &tokens[i]
-	}
-
-	parser := NewParser(tokenPtrs)
-	expression, err := parser.Parse()
-	if err != nil {
+func run(interpreter *Interpreter, source string) {
+	err := interpreter.Run(source)
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
 		fmt.Println("Error during parsing:", err)
-		return
 	}
-
-	interpreter.Interpret(expression)
 }
 
 func printEnvironment(env *Environment) {
@@ -87,20 +85,3 @@ func printEnvironment(env *Environment) {
 		printEnvironment(env.enclosing)
 	}
 }
-
-func runtimeError(err error) {
-	fmt.Println(err.Error())
-	hadRuntimeError = true
-}
-
-func Report(line int, where, message string) {
-	fmt.Printf("[line %d] Error%s: %s\n", line, where, message)
-}
-
-func parseError(token *Token, message string) {
-	if token.Type == EOF {
-		fmt.Printf("[line %d] Error at end: %s\n", token.Line, message)
-	} else {
-		fmt.Printf("[line %d] Error at '%s': %s\n", token.Line, token.Lexeme, message)
-	}
-}
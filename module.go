@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Module is the namespace created by an import statement. Only names the
+// module exports are visible through it.
+type Module struct {
+	name        string
+	path        string
+	environment *Environment
+	exports     map[string]bool
+	// err is the error the module failed with, if any. A failed module is
+	// not run again when it is imported a second time.
+	err error
+}
+
+func (m *Module) Get(name *Token) (interface{}, error) {
+	if !m.exports[name.Lexeme] {
+		return nil, fmt.Errorf("Module '%s' does not export '%s'.", m.name, name.Lexeme)
+	}
+	return m.environment.Get(name)
+}
+
+func (m *Module) Set(name *Token, value interface{}) error {
+	return fmt.Errorf("Cannot assign to '%s' of module '%s'.", name.Lexeme, m.name)
+}
+
+func (m *Module) String() string {
+	return fmt.Sprintf("<module %s>", m.name)
+}
+
+// SetModuleLoader sets where imported modules are resolved and read
+// from. Interpreters granted CapabilityFileSystem load from the OS
+// filesystem by default; others cannot import until a loader is set.
+func (i *Interpreter) SetModuleLoader(loader ModuleLoader) {
+	i.loader = loader
+}
+
+// RunFile runs the script at path, read through the module loader. Its
+// imports are resolved relative to the script.
+func (i *Interpreter) RunFile(path string) error {
+	return i.RunFileContext(context.Background(), path)
+}
+
+func (i *Interpreter) RunFileContext(ctx context.Context, path string) error {
+	if i.loader == nil {
+		return errNoModuleLoader
+	}
+	if resolved, err := i.loader.Resolve("", path); err == nil {
+		path = resolved
+	}
+	source, err := i.loader.Load(path)
+	if err != nil {
+		return err
+	}
+
+	i.importing = append(i.importing, path)
+	defer func() { i.importing = i.importing[:len(i.importing)-1] }()
+
+	previous := i.file
+	defer func() { i.file = previous }()
+	i.file = path
+	return i.RunContext(ctx, string(source))
+}
+
+func (i *Interpreter) VisitImportStmt(stmt *Import) (interface{}, error) {
+	module, err := i.importModule(stmt)
+	if err != nil {
+		return nil, err
+	}
+	i.environment.Define(stmt.Name.Lexeme, module)
+	return nil, nil
+}
+
+func (i *Interpreter) VisitExportStmt(stmt *Export) (interface{}, error) {
+	if _, err := i.execute(stmt.Declaration); err != nil {
+		return nil, err
+	}
+	if i.module != nil {
+		if stmt.Declaration.Pattern != nil {
+			for _, name := range stmt.Declaration.Pattern.Names() {
+				i.module.exports[name.Lexeme] = true
+			}
+		} else {
+			i.module.exports[stmt.Declaration.Name.Lexeme] = true
+		}
+	}
+	return nil, nil
+}
+
+// errNoModuleLoader is returned when a file is run or imported by an
+// interpreter without a module loader.
+var errNoModuleLoader = errors.New("No module loader is set.")
+
+func (i *Interpreter) importModule(stmt *Import) (*Module, error) {
+	if i.loader == nil {
+		return nil, i.error(stmt.Path, fmt.Sprintf("Cannot import '%s': %s", stmt.Path.Literal, errNoModuleLoader))
+	}
+	path, err := i.loader.Resolve(i.file, stmt.Path.Literal.(string))
+	if err != nil {
+		return nil, i.error(stmt.Path, err.Error())
+	}
+
+	for n, importing := range i.importing {
+		if importing == path {
+			cycle := append(append([]string{}, i.importing[n:]...), path)
+			return nil, i.error(stmt.Path, fmt.Sprintf("Import cycle: %s.", strings.Join(cycle, " -> ")))
+		}
+	}
+	if module, ok := i.modules[path]; ok {
+		if module.err != nil {
+			return nil, i.error(stmt.Path, fmt.Sprintf("Module '%s' failed to load.", path))
+		}
+		return module, nil
+	}
+
+	source, err := i.loader.Load(path)
+	if err != nil {
+		return nil, i.error(stmt.Path, fmt.Sprintf("Could not read module: %s.", err.Error()))
+	}
+
+	statements, err := i.parse(string(source))
+	if err != nil {
+		return nil, i.error(stmt.Path, fmt.Sprintf("Could not parse module '%s'.", path))
+	}
+
+	module := &Module{
+		name:        stmt.Name.Lexeme,
+		path:        path,
+		environment: NewEnvironment(i.builtins),
+		exports:     make(map[string]bool),
+	}
+
+	previousEnvironment, previousFile, previousModule := i.environment, i.file, i.module
+	defer func() {
+		i.environment, i.file, i.module = previousEnvironment, previousFile, previousModule
+		i.importing = i.importing[:len(i.importing)-1]
+	}()
+	i.environment, i.file, i.module = module.environment, path, module
+	i.importing = append(i.importing, path)
+	i.modules[path] = module
+
+	i.deferred = append(i.deferred, nil)
+	for _, statement := range statements {
+		if _, err = i.execute(statement); err != nil {
+			break
+		}
+	}
+	if err = i.runDeferred(err); err != nil {
+		// A module stopped by a limit may succeed on a later run, which
+		// gets a fresh budget.
+		var limit *LimitExceeded
+		if errors.As(err, &limit) {
+			delete(i.modules, path)
+		} else {
+			module.err = err
+		}
+		return nil, err
+	}
+	return module, nil
+}
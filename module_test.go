@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var testModules = fstest.MapFS{
+	"lib/counter.lango": {Data: []byte(`
+		var count = 0;
+		export fun next() { count += 1; return count; }
+	`)},
+	"lib/broken.lango": {Data: []byte(`
+		print "loading broken";
+		throw "boom";
+	`)},
+}
+
+func newModuleInterpreter() *Interpreter {
+	interpreter := NewInterpreter(Options{})
+	interpreter.SetModuleLoader(NewFSLoader(testModules))
+	return interpreter
+}
+
+func TestImportExports(t *testing.T) {
+	stdout, stderr, _ := runScript(newModuleInterpreter(), `
+		import "lib/counter.lango" as counter;
+		import "lib/counter.lango" as again;
+		counter.next();
+		print again.next();
+		print counter.count;
+	`)
+	if stdout != "2\n" {
+		t.Errorf("stdout = %q, want %q", stdout, "2\n")
+	}
+	if want := "Module 'counter' does not export 'count'."; !strings.Contains(stderr, want) {
+		t.Errorf("stderr = %q, want it to contain %q", stderr, want)
+	}
+}
+
+func TestFailedModuleRunsOnce(t *testing.T) {
+	stdout, stderr, _ := runScript(newModuleInterpreter(), `
+		import "lib/broken.lango" as broken;
+		import "lib/broken.lango" as again;
+	`)
+	if stdout != "loading broken\n" {
+		t.Errorf("stdout = %q, want the module to run once", stdout)
+	}
+	if want := "Uncaught exception: boom"; !strings.Contains(stderr, want) {
+		t.Errorf("stderr = %q, want it to contain %q", stderr, want)
+	}
+	if want := "Module 'lib/broken.lango' failed to load."; !strings.Contains(stderr, want) {
+		t.Errorf("stderr = %q, want it to contain %q", stderr, want)
+	}
+}
+
+func TestImportWithoutLoader(t *testing.T) {
+	interpreter := NewInterpreter(Options{})
+	stdout, stderr, err := runScript(interpreter, `import "/etc/hostname" as host; print "after";`)
+	if err == nil || stdout != "after\n" {
+		t.Errorf("Run() = %v, stdout %q, want the import to fail", err, stdout)
+	}
+	if want := "[line 1] Runtime error at '\"/etc/hostname\"': Cannot import '/etc/hostname': No module loader is set.\n"; stderr != want {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+	if err := interpreter.RunFile("/etc/hostname"); err != errNoModuleLoader {
+		t.Errorf("RunFile() = %v, want %v", err, errNoModuleLoader)
+	}
+}
+
+func TestFileSystemCapabilityLoadsModules(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "lib.lango"), []byte(`export var answer = 42;`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	main := filepath.Join(dir, "main.lango")
+	if err := os.WriteFile(main, []byte(`import "lib.lango" as lib; print lib.answer;`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr strings.Builder
+	interpreter := NewInterpreter(Options{Capabilities: CapabilityFileSystem})
+	interpreter.SetOutput(&stdout, &stderr)
+	if err := interpreter.RunFile(main); err != nil || stdout.String() != "42\n" {
+		t.Errorf("RunFile() = %v, stdout %q, stderr %q", err, stdout.String(), stderr.String())
+	}
+}
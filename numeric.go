@@ -0,0 +1,364 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// Lango has two numeric types. Integers are int64 values that grow into
+// *big.Int when a result overflows, and shrink back when it fits again.
+// Floats are float64. Mixing the two promotes the integer to a float.
+
+func isNumber(value interface{}) bool {
+	switch value.(type) {
+	case int64, *big.Int, float64:
+		return true
+	}
+	return false
+}
+
+func isInteger(value interface{}) bool {
+	switch value.(type) {
+	case int64, *big.Int:
+		return true
+	}
+	return false
+}
+
+func isNaN(value interface{}) bool {
+	f, ok := value.(float64)
+	return ok && math.IsNaN(f)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f, true
+	}
+	return 0, false
+}
+
+func toBigInt(value interface{}) (*big.Int, bool) {
+	switch v := value.(type) {
+	case int64:
+		return big.NewInt(v), true
+	case *big.Int:
+		return v, true
+	}
+	return nil, false
+}
+
+// normalizeInt returns n as an int64 when it fits.
+func normalizeInt(n *big.Int) interface{} {
+	if n.IsInt64() {
+		return n.Int64()
+	}
+	return n
+}
+
+// parseInteger parses an integer literal in the given base, falling back
+// to a *big.Int when it does not fit in an int64.
+func parseInteger(text string, base int) (interface{}, bool) {
+	if n, err := strconv.ParseInt(text, base, 64); err == nil {
+		return n, true
+	}
+	n, ok := new(big.Int).SetString(text, base)
+	if !ok {
+		return nil, false
+	}
+	return normalizeInt(n), true
+}
+
+func (i *Interpreter) arithmetic(operator *Token, left, right interface{}) (interface{}, error) {
+	if !isNumber(left) || !isNumber(right) {
+		return nil, i.error(operator, "Operands must be numbers.")
+	}
+	if isInteger(left) && isInteger(right) && operator.Type != SLASH {
+		return i.integerArithmetic(operator, left, right)
+	}
+
+	l, _ := toFloat(left)
+	r, _ := toFloat(right)
+	switch operator.Type {
+	case PLUS:
+		return l + r, nil
+	case MINUS:
+		return l - r, nil
+	case STAR:
+		return l * r, nil
+	case SLASH:
+		if r == 0 {
+			return nil, i.error(operator, "Division by zero.")
+		}
+		return l / r, nil
+	case SLASH_SLASH:
+		if r == 0 {
+			return nil, i.error(operator, "Division by zero.")
+		}
+		return math.Floor(l / r), nil
+	case MOD:
+		if r == 0 {
+			return nil, i.error(operator, "Modulo by zero.")
+		}
+		m := math.Mod(l, r)
+		if m != 0 && (m < 0) != (r < 0) {
+			m += r
+		}
+		return m, nil
+	}
+	return nil, i.error(operator, "Unexpected arithmetic operator.")
+}
+
+func (i *Interpreter) integerArithmetic(operator *Token, left, right interface{}) (interface{}, error) {
+	if l, ok := left.(int64); ok {
+		if r, ok := right.(int64); ok {
+			if result, ok, err := i.smallIntegerArithmetic(operator, l, r); ok || err != nil {
+				return result, err
+			}
+		}
+	}
+
+	l, _ := toBigInt(left)
+	r, _ := toBigInt(right)
+	result := new(big.Int)
+	switch operator.Type {
+	case PLUS, MINUS:
+		if l.BitLen()+1 > maxIntegerBits || r.BitLen()+1 > maxIntegerBits {
+			return nil, i.error(operator, "Integer result too large.")
+		}
+		if operator.Type == PLUS {
+			result.Add(l, r)
+		} else {
+			result.Sub(l, r)
+		}
+	case STAR:
+		if l.BitLen()+r.BitLen() > maxIntegerBits {
+			return nil, i.error(operator, "Integer result too large.")
+		}
+		result.Mul(l, r)
+	case SLASH_SLASH, MOD:
+		if r.Sign() == 0 {
+			if operator.Type == MOD {
+				return nil, i.error(operator, "Modulo by zero.")
+			}
+			return nil, i.error(operator, "Division by zero.")
+		}
+		quotient, remainder := new(big.Int).QuoRem(l, r, new(big.Int))
+		if remainder.Sign() != 0 && (remainder.Sign() < 0) != (r.Sign() < 0) {
+			quotient.Sub(quotient, big.NewInt(1))
+			remainder.Add(remainder, r)
+		}
+		if operator.Type == MOD {
+			result = remainder
+		} else {
+			result = quotient
+		}
+	default:
+		return nil, i.error(operator, "Unexpected arithmetic operator.")
+	}
+	return normalizeInt(result), nil
+}
+
+// smallIntegerArithmetic computes int64 results directly, reporting false
+// when the result overflows and must be computed with big integers.
+func (i *Interpreter) smallIntegerArithmetic(operator *Token, l, r int64) (interface{}, bool, error) {
+	switch operator.Type {
+	case PLUS:
+		sum := l + r
+		return sum, (l^sum)&(r^sum) >= 0, nil
+	case MINUS:
+		difference := l - r
+		return difference, (l^r)&(l^difference) >= 0, nil
+	case STAR:
+		if l == 0 || r == 0 {
+			return int64(0), true, nil
+		}
+		product := l * r
+		overflow := product/r != l || (l == -1 && r == math.MinInt64) || (r == -1 && l == math.MinInt64)
+		return product, !overflow, nil
+	case SLASH_SLASH:
+		if r == 0 {
+			return nil, false, i.error(operator, "Division by zero.")
+		}
+		if l == math.MinInt64 && r == -1 {
+			return nil, false, nil
+		}
+		quotient := l / r
+		if l%r != 0 && (l < 0) != (r < 0) {
+			quotient--
+		}
+		return quotient, true, nil
+	case MOD:
+		if r == 0 {
+			return nil, false, i.error(operator, "Modulo by zero.")
+		}
+		remainder := l % r
+		if remainder != 0 && (remainder < 0) != (r < 0) {
+			remainder += r
+		}
+		return remainder, true, nil
+	}
+	return nil, false, nil
+}
+
+func (i *Interpreter) negate(operator *Token, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case float64:
+		return -v, nil
+	case int64:
+		if v != math.MinInt64 {
+			return -v, nil
+		}
+		return new(big.Int).Neg(big.NewInt(v)), nil
+	case *big.Int:
+		return normalizeInt(new(big.Int).Neg(v)), nil
+	}
+	return nil, i.error(operator, "Operand must be a number.")
+}
+
+// maxIntegerBits bounds the integers arithmetic, left shifts and powers
+// produce so a single expression cannot allocate an unbounded integer.
+const maxIntegerBits = 1 << 20
+
+// power raises left to right. Integers raised to a non-negative integer
+// stay exact; anything else is computed with floats.
+func (i *Interpreter) power(operator *Token, left, right interface{}) (interface{}, error) {
+	if !isNumber(left) || !isNumber(right) {
+		return nil, i.error(operator, "Operands must be numbers.")
+	}
+
+	base, baseOk := toBigInt(left)
+	exponent, exponentOk := toBigInt(right)
+	if !baseOk || !exponentOk || exponent.Sign() < 0 {
+		l, _ := toFloat(left)
+		r, _ := toFloat(right)
+		return math.Pow(l, r), nil
+	}
+
+	if base.CmpAbs(big.NewInt(1)) > 0 {
+		if !exponent.IsInt64() || exponent.Int64() > maxIntegerBits/int64(base.BitLen()) {
+			return nil, i.error(operator, "Exponent too large.")
+		}
+	}
+	return normalizeInt(new(big.Int).Exp(base, exponent, nil)), nil
+}
+
+func (i *Interpreter) bitwise(operator *Token, left, right interface{}) (interface{}, error) {
+	if !isInteger(left) || !isInteger(right) {
+		return nil, i.error(operator, "Operands must be integers.")
+	}
+
+	if l, ok := left.(int64); ok {
+		if r, ok := right.(int64); ok {
+			switch operator.Type {
+			case AMPERSAND:
+				return l & r, nil
+			case PIPE:
+				return l | r, nil
+			case CARET:
+				return l ^ r, nil
+			}
+		}
+	}
+
+	l, _ := toBigInt(left)
+	r, _ := toBigInt(right)
+	result := new(big.Int)
+	switch operator.Type {
+	case AMPERSAND:
+		result.And(l, r)
+	case PIPE:
+		result.Or(l, r)
+	case CARET:
+		result.Xor(l, r)
+	case LESS_LESS, GREATER_GREATER:
+		if r.Sign() < 0 {
+			return nil, i.error(operator, "Shift count must not be negative.")
+		}
+		if operator.Type == GREATER_GREATER {
+			if !r.IsInt64() || r.Int64() > int64(l.BitLen()) {
+				r = big.NewInt(int64(l.BitLen()))
+			}
+			result.Rsh(l, uint(r.Int64()))
+			break
+		}
+		if l.Sign() == 0 {
+			return int64(0), nil
+		}
+		if !r.IsInt64() || r.Int64() > maxIntegerBits {
+			return nil, i.error(operator, "Shift count too large.")
+		}
+		result.Lsh(l, uint(r.Int64()))
+	default:
+		return nil, i.error(operator, "Unexpected bitwise operator.")
+	}
+	return normalizeInt(result), nil
+}
+
+func (i *Interpreter) complement(operator *Token, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int64:
+		return ^v, nil
+	case *big.Int:
+		return normalizeInt(new(big.Int).Not(v)), nil
+	}
+	return nil, i.error(operator, "Operand must be an integer.")
+}
+
+// compareNumbers returns -1, 0 or 1 as a is less than, equal to or greater
+// than b. Both must be numbers.
+func compareNumbers(a, b interface{}) int {
+	if l, ok := a.(int64); ok {
+		if r, ok := b.(int64); ok {
+			switch {
+			case l < r:
+				return -1
+			case l > r:
+				return 1
+			}
+			return 0
+		}
+	}
+	if isInteger(a) && isInteger(b) {
+		l, _ := toBigInt(a)
+		r, _ := toBigInt(b)
+		return l.Cmp(r)
+	}
+
+	l, _ := toFloat(a)
+	r, _ := toFloat(b)
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+	return 0
+}
+
+func (i *Interpreter) compare(operator *Token, left, right interface{}) (interface{}, error) {
+	if !isNumber(left) || !isNumber(right) {
+		return nil, i.error(operator, "Operands must be numbers.")
+	}
+	if isNaN(left) || isNaN(right) {
+		return false, nil
+	}
+	result := compareNumbers(left, right)
+	switch operator.Type {
+	case GREATER:
+		return result > 0, nil
+	case GREATER_EQUAL:
+		return result >= 0, nil
+	case LESS:
+		return result < 0, nil
+	case LESS_EQUAL:
+		return result <= 0, nil
+	}
+	return nil, i.error(operator, "Unexpected comparison operator.")
+}
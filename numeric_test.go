@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntegerArithmetic(t *testing.T) {
+	stdout, stderr, err := runScript(NewInterpreter(Options{}), `
+		print 9223372036854775807 + 1;
+		print -7 // 2;
+		print -7 % 2;
+		print 2 ** 100;
+		print 1 << 64 >> 63;
+	`)
+	if err != nil {
+		t.Fatalf("Run() = %v, stderr %q", err, stderr)
+	}
+	want := "9223372036854775808\n-4\n1\n1267650600228229401496703205376\n2\n"
+	if stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+}
+
+func TestFloorDivisionAndComments(t *testing.T) {
+	tests := []struct {
+		source string
+		stdout string
+	}{
+		{`print 7 // 2;`, "3\n"},
+		{`print 7.5 // 2;`, "3\n"},
+		{`var x = 9; print x // 2 // 2;`, "2\n"},
+		{`print (1 + 6) // 2;`, "3\n"},
+		{`var f = fun () { return 7; }; print f() // 2;`, "3\n"},
+		{`print 7; // 2;`, "7\n"},
+		{"print 7\n// 2;\n;", "7\n"},
+		{`// print 7 // 2;`, ""},
+		{`var x = 1; { // comment after a brace
+			print x; }`, "1\n"},
+	}
+	for _, test := range tests {
+		stdout, stderr, err := runScript(NewInterpreter(Options{}), test.source)
+		if err != nil || stdout != test.stdout {
+			t.Errorf("%s: Run() = %v, stdout %q, want %q (stderr %q)", test.source, err, stdout, test.stdout, stderr)
+		}
+	}
+}
+
+func TestIntegerSizeLimit(t *testing.T) {
+	tests := []string{
+		`var x = 3; while (true) x = x * x;`,
+		`var x = 3; while (true) x *= x;`,
+		`var x = 1 << 1048575; while (true) x = x + x;`,
+		`var x = -(1 << 1048575); while (true) x = x - (1 << 1048575);`,
+		`print 2 ** 2000000;`,
+		`print 1 << 2000000;`,
+	}
+	for _, source := range tests {
+		start := time.Now()
+		_, stderr, err := runScript(NewInterpreter(Options{}), source)
+		if err == nil || !strings.Contains(stderr, "too large.") {
+			t.Errorf("%s: Run() = %v, stderr %q, want a size error", source, err, stderr)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("%s: took %v", source, elapsed)
+		}
+	}
+}
+
+func TestIntegerSizeErrorCanBeCaught(t *testing.T) {
+	stdout, stderr, _ := runScript(NewInterpreter(Options{}), `
+		var x = 3;
+		try { while (true) x = x * x; } catch (e) { print e.message; }
+	`)
+	if stdout != "Integer result too large.\n" {
+		t.Errorf("stdout = %q, stderr %q", stdout, stderr)
+	}
+}
+
+func TestMalformedNumbers(t *testing.T) {
+	tests := []struct {
+		literal string
+		message string
+	}{
+		{"0x", "Missing digits after '0x'."},
+		{"0b2", "Invalid digit '2' in binary literal."},
+		{"1__0", "Consecutive '_' in number."},
+		{"1_", "Number cannot end with '_'."},
+		{"1e", "Missing digits in exponent."},
+		{"0o9", "Invalid digit '9' in octal literal."},
+	}
+	for _, test := range tests {
+		source := "print \"before\";\nprint " + test.literal + ";"
+		stdout, stderr, err := runScript(NewInterpreter(Options{}), source)
+		want := &ScanError{Line: 2, Message: test.message}
+		if scanErr, ok := err.(*ScanError); !ok || *scanErr != *want {
+			t.Errorf("%s: Run() = %v, want %v", test.literal, err, want)
+		}
+		if wantStderr := "[line 2] Error: " + test.message + "\n"; stderr != wantStderr {
+			t.Errorf("%s: stderr = %q, want %q", test.literal, stderr, wantStderr)
+		}
+		if stdout != "" {
+			t.Errorf("%s: stdout = %q, want nothing to run", test.literal, stdout)
+		}
+	}
+}
@@ -2,21 +2,51 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"math/big"
+	"os"
 )
 
+type ParseError struct {
+	Token   *Token
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parse error at '%s': %s", e.Token.Lexeme, e.Message)
+}
+
 type Parser struct {
 	tokens  []*Token
 	current int
+	errOut  io.Writer
+	// functionDepth counts the function bodies being parsed, so return
+	// can be rejected outside of them.
+	functionDepth int
+	// breakDepth counts the loops and switches break can leave from the
+	// current function body.
+	breakDepth int
+	// deferDepth counts the deferred statements being parsed in the
+	// current function body, which return and break cannot leave.
+	deferDepth int
+	// scopes maps the names declared in each enclosing scope to whether
+	// they are constants, so assignments to them can be rejected early.
+	scopes []map[string]bool
 }
 
 func NewParser(tokens []*Token) *Parser {
-	return &Parser{tokens, 0}
+	return &Parser{tokens: tokens, errOut: os.Stderr, scopes: []map[string]bool{{}}}
+}
+
+// SetErrorOutput sets where parse errors are reported.
+func (p *Parser) SetErrorOutput(w io.Writer) {
+	p.errOut = w
 }
 
 func (p *Parser) Parse() ([]Stmt, error) {
 	statements := []Stmt{}
 	for !p.isAtEnd() {
-		decl, err := p.declaration()
+		decl, err := p.topLevelDeclaration()
 		if err != nil {
 			return nil, err
 		}
@@ -25,18 +55,85 @@ func (p *Parser) Parse() ([]Stmt, error) {
 	return statements, nil
 }
 
+func (p *Parser) topLevelDeclaration() (Stmt, error) {
+	if p.match(IMPORT) {
+		return p.importStatement()
+	} else if p.match(EXPORT) {
+		return p.exportDeclaration()
+	}
+	return p.declaration()
+}
+
 func (p *Parser) declaration() (Stmt, error) {
 	if p.match(VAR) {
 		return p.varDeclaration()
+	} else if p.match(CONST, LET) {
+		return p.constDeclaration()
+	} else if p.check(FUN) && p.checkNext(IDENTIFIER) {
+		p.advance()
+		return p.funDeclaration()
+	} else if p.check(IMPORT) || p.check(EXPORT) {
+		return nil, p.error(p.peek(), fmt.Sprintf("'%s' is only allowed at the top level.", p.peek().Lexeme))
 	}
 	return p.statement()
 }
 
+func (p *Parser) importStatement() (Stmt, error) {
+	keyword := p.previous()
+	path, err := p.consume(STRING, "Expect module path after 'import'.")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(AS, "Expect 'as' after module path."); err != nil {
+		return nil, err
+	}
+	name, err := p.consume(IDENTIFIER, "Expect module name after 'as'.")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.declare(name, false); err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(SEMICOLON, "Expect ';' after import."); err != nil {
+		return nil, err
+	}
+	return &Import{Keyword: keyword, Path: path, Name: name}, nil
+}
+
+func (p *Parser) exportDeclaration() (Stmt, error) {
+	keyword := p.previous()
+	if p.check(FUN) && p.checkNext(IDENTIFIER) {
+		p.advance()
+		decl, err := p.funDeclaration()
+		if err != nil {
+			return nil, err
+		}
+		return &Export{Keyword: keyword, Declaration: decl.(*Var)}, nil
+	}
+	var decl Stmt
+	var err error
+	if p.match(CONST, LET) {
+		decl, err = p.constDeclaration()
+	} else if _, err = p.consume(VAR, "Expect declaration after 'export'."); err == nil {
+		decl, err = p.varDeclaration()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Export{Keyword: keyword, Declaration: decl.(*Var)}, nil
+}
+
 func (p *Parser) varDeclaration() (Stmt, error) {
+	if p.check(LEFT_BRACKET) || p.check(LEFT_BRACE) {
+		return p.destructuringDeclaration(nil)
+	}
 	name, err := p.consume(IDENTIFIER, "Expect variable name.")
 	if err != nil {
 		return nil, err
 	}
+	if err := p.declare(name, false); err != nil {
+		return nil, err
+	}
 	var initializer Expr
 	if p.match(EQUAL) {
 		initializer, err = p.expression()
@@ -51,6 +148,338 @@ func (p *Parser) varDeclaration() (Stmt, error) {
 	return &Var{Name: name, Initializer: initializer}, nil
 }
 
+// constDeclaration parses the rest of a const or let declaration.
+func (p *Parser) constDeclaration() (Stmt, error) {
+	keyword := p.previous()
+	if p.check(LEFT_BRACKET) || p.check(LEFT_BRACE) {
+		return p.destructuringDeclaration(keyword)
+	}
+	name, err := p.consume(IDENTIFIER, "Expect constant name.")
+	if err != nil {
+		return nil, err
+	}
+	if !p.check(EQUAL) {
+		return nil, p.error(name, fmt.Sprintf("Constant '%s' must be initialized.", name.Lexeme))
+	}
+	p.advance()
+	initializer, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(SEMICOLON, "Expect ';' after constant declaration."); err != nil {
+		return nil, err
+	}
+	if err := p.declare(name, true); err != nil {
+		return nil, err
+	}
+	return &Var{Keyword: keyword, Name: name, Initializer: initializer, Constant: true}, nil
+}
+
+// destructuringDeclaration parses a declaration of a pattern, which is
+// constant if keyword is set.
+func (p *Parser) destructuringDeclaration(keyword *Token) (Stmt, error) {
+	constant := keyword != nil
+	pattern, err := p.pattern()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(EQUAL, "Expect '=' after destructuring pattern."); err != nil {
+		return nil, err
+	}
+	initializer, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(SEMICOLON, "Expect ';' after variable declaration."); err != nil {
+		return nil, err
+	}
+	for _, name := range pattern.Names() {
+		if err := p.declare(name, constant); err != nil {
+			return nil, err
+		}
+	}
+	return &Var{Keyword: keyword, Pattern: pattern, Initializer: initializer, Constant: constant}, nil
+}
+
+// pattern parses a name, a list pattern [a, b, ...rest] or a map pattern
+// {a, b}, rejecting names bound more than once.
+func (p *Parser) pattern() (*Pattern, error) {
+	pattern, err := p.subpattern()
+	if err != nil {
+		return nil, err
+	}
+	return pattern, p.checkDuplicateNames(pattern)
+}
+
+func (p *Parser) checkDuplicateNames(pattern *Pattern) error {
+	names := pattern.Names()
+	for n, name := range names {
+		for _, earlier := range names[:n] {
+			if earlier.Lexeme == name.Lexeme {
+				return p.error(name, fmt.Sprintf("Duplicate name '%s' in pattern.", name.Lexeme))
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Parser) subpattern() (*Pattern, error) {
+	if p.match(LEFT_BRACKET) {
+		pattern := &Pattern{Bracket: p.previous(), Elements: []*Pattern{}}
+		for !p.check(RIGHT_BRACKET) && !p.isAtEnd() {
+			if p.match(DOT_DOT_DOT) {
+				rest, err := p.consume(IDENTIFIER, "Expect name after '...'.")
+				if err != nil {
+					return nil, err
+				}
+				pattern.Rest = rest
+				if !p.check(RIGHT_BRACKET) {
+					return nil, p.error(p.peek(), "Rest element must be last.")
+				}
+				break
+			}
+			element, err := p.subpattern()
+			if err != nil {
+				return nil, err
+			}
+			pattern.Elements = append(pattern.Elements, element)
+			if !p.match(COMMA) {
+				break
+			}
+		}
+		if _, err := p.consume(RIGHT_BRACKET, "Expect ']' after list pattern."); err != nil {
+			return nil, err
+		}
+		return pattern, nil
+	}
+
+	if p.match(LEFT_BRACE) {
+		pattern := &Pattern{Bracket: p.previous(), Keys: []*Token{}}
+		for !p.check(RIGHT_BRACE) && !p.isAtEnd() {
+			key, err := p.consume(IDENTIFIER, "Expect key name in map pattern.")
+			if err != nil {
+				return nil, err
+			}
+			pattern.Keys = append(pattern.Keys, key)
+			if !p.match(COMMA) {
+				break
+			}
+		}
+		if _, err := p.consume(RIGHT_BRACE, "Expect '}' after map pattern."); err != nil {
+			return nil, err
+		}
+		return pattern, nil
+	}
+
+	name, err := p.consume(IDENTIFIER, "Expect variable name.")
+	if err != nil {
+		return nil, err
+	}
+	return &Pattern{Name: name}, nil
+}
+
+func (p *Parser) matchExpression() (Expr, error) {
+	keyword := p.previous()
+	if _, err := p.consume(LEFT_PAREN, "Expect '(' after 'match'."); err != nil {
+		return nil, err
+	}
+	subject, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(RIGHT_PAREN, "Expect ')' after match value."); err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(LEFT_BRACE, "Expect '{' before match arms."); err != nil {
+		return nil, err
+	}
+
+	arms := []*MatchArm{}
+	exhaustive := false
+	for !p.check(RIGHT_BRACE) && !p.isAtEnd() {
+		arm, err := p.matchArm()
+		if err != nil {
+			return nil, err
+		}
+		arms = append(arms, arm)
+		if arm.Guard == nil && arm.Pattern.isIrrefutable() {
+			exhaustive = true
+		}
+
+		if !p.match(COMMA) && arm.Body == nil {
+			break
+		}
+	}
+	if _, err := p.consume(RIGHT_BRACE, "Expect '}' after match arms."); err != nil {
+		return nil, err
+	}
+
+	if !exhaustive {
+		p.warning(keyword, "Match has no wildcard arm, so a value no arm matches is a runtime error.")
+	}
+	return &Match{Keyword: keyword, Subject: subject, Arms: arms}, nil
+}
+
+func (p *Parser) matchArm() (*MatchArm, error) {
+	p.beginScope()
+	defer p.endScope()
+
+	arm := &MatchArm{}
+	var err error
+	if arm.Pattern, err = p.matchPattern(); err != nil {
+		return nil, err
+	}
+	for _, name := range arm.Pattern.Names() {
+		p.declare(name, false)
+	}
+	if p.match(IF) {
+		if arm.Guard, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.consume(ARROW, "Expect '=>' after match pattern."); err != nil {
+		return nil, err
+	}
+	if p.check(LEFT_BRACE) {
+		arm.Body, err = p.block("Expect '{' before match arm body.")
+	} else {
+		arm.Value, err = p.expression()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return arm, nil
+}
+
+// matchPattern parses the pattern of a match arm: alternatives joined by
+// | of literals, _, names, list patterns and map patterns.
+func (p *Parser) matchPattern() (*Pattern, error) {
+	pattern, err := p.matchAlternative()
+	if err != nil {
+		return nil, err
+	}
+	if !p.check(PIPE) {
+		return pattern, p.checkDuplicateNames(pattern)
+	}
+
+	alternatives := &Pattern{Alternatives: []*Pattern{pattern}}
+	for p.match(PIPE) {
+		pattern, err := p.matchAlternative()
+		if err != nil {
+			return nil, err
+		}
+		alternatives.Alternatives = append(alternatives.Alternatives, pattern)
+	}
+	for _, alternative := range alternatives.Alternatives {
+		if names := alternative.Names(); len(names) > 0 {
+			return nil, p.error(names[0], "Alternatives in a pattern cannot bind names.")
+		}
+	}
+	return alternatives, nil
+}
+
+func (p *Parser) matchAlternative() (*Pattern, error) {
+	switch {
+	case p.match(NUMBER):
+		return &Pattern{Value: &Literal{Value: p.previous().Literal, Lexeme: p.previous().Lexeme}}, nil
+	case p.match(STRING):
+		return &Pattern{Value: &Literal{Value: p.previous().Literal}}, nil
+	case p.match(TRUE):
+		return &Pattern{Value: &Literal{Value: true}}, nil
+	case p.match(FALSE):
+		return &Pattern{Value: &Literal{Value: false}}, nil
+	case p.match(NIL):
+		return &Pattern{Value: &Literal{Value: nil}}, nil
+	case p.match(MINUS):
+		operator := p.previous()
+		number, err := p.consume(NUMBER, "Expect number after '-' in pattern.")
+		if err != nil {
+			return nil, err
+		}
+		return &Pattern{Value: &Unary{operator, &Literal{Value: number.Literal, Lexeme: number.Lexeme}}}, nil
+	case p.match(IDENTIFIER):
+		if p.previous().Lexeme == "_" {
+			return &Pattern{Wildcard: true}, nil
+		}
+		return &Pattern{Name: p.previous()}, nil
+	}
+
+	if p.match(LEFT_BRACKET) {
+		pattern := &Pattern{Bracket: p.previous(), Elements: []*Pattern{}}
+		for !p.check(RIGHT_BRACKET) && !p.isAtEnd() {
+			if p.match(DOT_DOT_DOT) {
+				rest, err := p.consume(IDENTIFIER, "Expect name after '...'.")
+				if err != nil {
+					return nil, err
+				}
+				pattern.Rest = rest
+				if !p.check(RIGHT_BRACKET) {
+					return nil, p.error(p.peek(), "Rest element must be last.")
+				}
+				break
+			}
+			element, err := p.matchPattern()
+			if err != nil {
+				return nil, err
+			}
+			pattern.Elements = append(pattern.Elements, element)
+			if !p.match(COMMA) {
+				break
+			}
+		}
+		if _, err := p.consume(RIGHT_BRACKET, "Expect ']' after list pattern."); err != nil {
+			return nil, err
+		}
+		return pattern, nil
+	}
+
+	if p.match(LEFT_BRACE) {
+		pattern := &Pattern{Bracket: p.previous(), Keys: []*Token{}, Fields: []*Pattern{}}
+		for !p.check(RIGHT_BRACE) && !p.isAtEnd() {
+			key, err := p.consume(IDENTIFIER, "Expect key name in map pattern.")
+			if err != nil {
+				return nil, err
+			}
+			var field *Pattern
+			if p.match(COLON) {
+				if field, err = p.matchPattern(); err != nil {
+					return nil, err
+				}
+			}
+			pattern.Keys = append(pattern.Keys, key)
+			pattern.Fields = append(pattern.Fields, field)
+			if !p.match(COMMA) {
+				break
+			}
+		}
+		if _, err := p.consume(RIGHT_BRACE, "Expect '}' after map pattern."); err != nil {
+			return nil, err
+		}
+		return pattern, nil
+	}
+
+	return nil, p.error(p.peek(), "Expect pattern.")
+}
+
+// funDeclaration parses fun name(params) { body }, which declares name
+// as a variable holding the function.
+func (p *Parser) funDeclaration() (Stmt, error) {
+	keyword := p.previous()
+	name, err := p.consume(IDENTIFIER, "Expect function name.")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.declare(name, false); err != nil {
+		return nil, err
+	}
+	function, err := p.function(keyword)
+	if err != nil {
+		return nil, err
+	}
+	function.(*Lambda).Name = name
+	return &Var{Name: name, Initializer: function}, nil
+}
+
 func (p *Parser) statement() (Stmt, error) {
 	if p.match(PRINT) {
 		return p.printStatement()
@@ -60,12 +489,254 @@ func (p *Parser) statement() (Stmt, error) {
 		return p.whileStatement()
 	} else if p.match(FOR) {
 		return p.forStatement()
+	} else if p.match(RETURN) {
+		return p.returnStatement()
+	} else if p.match(SWITCH) {
+		return p.switchStatement()
+	} else if p.match(BREAK) {
+		return p.breakStatement()
+	} else if p.check(FALLTHROUGH) {
+		return nil, p.error(p.peek(), "'fallthrough' must be the last statement in a case.")
+	} else if p.match(THROW) {
+		return p.throwStatement()
+	} else if p.match(DEFER) {
+		return p.deferStatement()
+	} else if p.match(TRY) {
+		return p.tryStatement()
 	} else if p.match(LEFT_BRACE) {
 		return p.blockStatement()
 	}
 	return p.expressionStatement()
 }
 
+func (p *Parser) deferStatement() (Stmt, error) {
+	keyword := p.previous()
+	if p.check(DEFER) || p.check(RETURN) || p.check(BREAK) {
+		return nil, p.error(p.peek(), fmt.Sprintf("Cannot defer a '%s' statement.", p.peek().Lexeme))
+	}
+	breakDepth := p.breakDepth
+	p.breakDepth = 0
+	p.deferDepth++
+	stmt, err := p.statement()
+	p.breakDepth = breakDepth
+	p.deferDepth--
+	if err != nil {
+		return nil, err
+	}
+	return &Defer{Keyword: keyword, Statement: stmt}, nil
+}
+
+func (p *Parser) returnStatement() (Stmt, error) {
+	keyword := p.previous()
+	if p.functionDepth == 0 {
+		return nil, p.error(keyword, "Can't return from top-level code.")
+	}
+	if p.deferDepth > 0 {
+		return nil, p.error(keyword, "Can't return from a deferred statement.")
+	}
+	var value Expr
+	if !p.check(SEMICOLON) {
+		var err error
+		if value, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.consume(SEMICOLON, "Expect ';' after return value."); err != nil {
+		return nil, err
+	}
+	return &Return{Keyword: keyword, Value: value}, nil
+}
+
+func (p *Parser) breakStatement() (Stmt, error) {
+	keyword := p.previous()
+	if p.breakDepth == 0 && p.deferDepth > 0 {
+		return nil, p.error(keyword, "Can't break out of a deferred statement.")
+	}
+	if p.breakDepth == 0 {
+		return nil, p.error(keyword, "Can't use 'break' outside of a loop or switch.")
+	}
+	if _, err := p.consume(SEMICOLON, "Expect ';' after 'break'."); err != nil {
+		return nil, err
+	}
+	return &Break{Keyword: keyword}, nil
+}
+
+func (p *Parser) switchStatement() (Stmt, error) {
+	keyword := p.previous()
+	if _, err := p.consume(LEFT_PAREN, "Expect '(' after 'switch'."); err != nil {
+		return nil, err
+	}
+	subject, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(RIGHT_PAREN, "Expect ')' after switch value."); err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(LEFT_BRACE, "Expect '{' before switch body."); err != nil {
+		return nil, err
+	}
+
+	p.breakDepth++
+	defer func() { p.breakDepth-- }()
+
+	cases := []*Case{}
+	var constants []interface{}
+	hasDefault := false
+	for !p.check(RIGHT_BRACE) && !p.isAtEnd() {
+		clause := &Case{}
+		if p.match(DEFAULT) {
+			clause.Keyword = p.previous()
+			if hasDefault {
+				return nil, p.error(clause.Keyword, "Multiple default clauses in switch.")
+			}
+			hasDefault = true
+		} else {
+			var err error
+			if clause.Keyword, err = p.consume(CASE, "Expect 'case' or 'default' in switch."); err != nil {
+				return nil, err
+			}
+			for {
+				value, err := p.expression()
+				if err != nil {
+					return nil, err
+				}
+				if constant, ok := p.constantValue(value); ok {
+					for _, existing := range constants {
+						if valuesEqual(existing, constant) {
+							return nil, p.error(p.previous(), "Duplicate case value in switch.")
+						}
+					}
+					constants = append(constants, constant)
+				}
+				clause.Values = append(clause.Values, value)
+				if !p.match(COMMA) {
+					break
+				}
+			}
+		}
+		if _, err := p.consume(COLON, fmt.Sprintf("Expect ':' after '%s'.", clause.Keyword.Lexeme)); err != nil {
+			return nil, err
+		}
+
+		clause.Body = []Stmt{}
+		p.beginScope()
+		for !p.check(CASE) && !p.check(DEFAULT) && !p.check(RIGHT_BRACE) && !p.isAtEnd() {
+			if p.match(FALLTHROUGH) {
+				fallthroughToken := p.previous()
+				if _, err := p.consume(SEMICOLON, "Expect ';' after 'fallthrough'."); err != nil {
+					return nil, err
+				}
+				if p.check(RIGHT_BRACE) {
+					return nil, p.error(fallthroughToken, "Cannot fall through from the last case.")
+				}
+				if !p.check(CASE) && !p.check(DEFAULT) {
+					return nil, p.error(fallthroughToken, "'fallthrough' must be the last statement in a case.")
+				}
+				clause.Fallthrough = true
+				break
+			}
+			stmt, err := p.declaration()
+			if err != nil {
+				p.endScope()
+				return nil, err
+			}
+			clause.Body = append(clause.Body, stmt)
+		}
+		p.endScope()
+		cases = append(cases, clause)
+	}
+	if _, err := p.consume(RIGHT_BRACE, "Expect '}' after switch body."); err != nil {
+		return nil, err
+	}
+	return &Switch{Keyword: keyword, Subject: subject, Cases: cases}, nil
+}
+
+// constantValue returns the value of a literal expression, such as a
+// case value, so duplicates can be found without running the script.
+func (p *Parser) constantValue(expr Expr) (interface{}, bool) {
+	switch expr := expr.(type) {
+	case *Literal:
+		return expr.Value, true
+	case *Grouping:
+		return p.constantValue(expr.Expression)
+	case *Unary:
+		value, ok := p.constantValue(expr.Right)
+		if !ok || expr.Operator.Type != MINUS {
+			return nil, false
+		}
+		switch value := value.(type) {
+		case int64, *big.Int:
+			n, _ := toBigInt(value)
+			return normalizeInt(new(big.Int).Neg(n)), true
+		case float64:
+			return -value, true
+		}
+	}
+	return nil, false
+}
+
+func (p *Parser) throwStatement() (Stmt, error) {
+	keyword := p.previous()
+	value, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(SEMICOLON, "Expect ';' after thrown value."); err != nil {
+		return nil, err
+	}
+	return &Throw{Keyword: keyword, Value: value}, nil
+}
+
+func (p *Parser) tryStatement() (Stmt, error) {
+	stmt := &Try{}
+	var err error
+	if stmt.Body, err = p.block("Expect '{' after 'try'."); err != nil {
+		return nil, err
+	}
+
+	if p.match(CATCH) {
+		if _, err := p.consume(LEFT_PAREN, "Expect '(' after 'catch'."); err != nil {
+			return nil, err
+		}
+		if stmt.CatchName, err = p.consume(IDENTIFIER, "Expect error variable name."); err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(RIGHT_PAREN, "Expect ')' after error variable."); err != nil {
+			return nil, err
+		}
+		p.beginScope()
+		p.declare(stmt.CatchName, false)
+		stmt.CatchBody, err = p.block("Expect '{' before catch body.")
+		p.endScope()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.match(FINALLY) {
+		if stmt.FinallyBody, err = p.block("Expect '{' after 'finally'."); err != nil {
+			return nil, err
+		}
+	}
+
+	if stmt.CatchBody == nil && stmt.FinallyBody == nil {
+		return nil, p.error(p.peek(), "Expect 'catch' or 'finally' after try block.")
+	}
+	return stmt, nil
+}
+
+func (p *Parser) block(message string) (*Block, error) {
+	if _, err := p.consume(LEFT_BRACE, message); err != nil {
+		return nil, err
+	}
+	stmt, err := p.blockStatement()
+	if err != nil {
+		return nil, err
+	}
+	return stmt.(*Block), nil
+}
+
 func (p *Parser) printStatement() (Stmt, error) {
 	value, err := p.expression()
 	if err != nil {
@@ -79,6 +750,8 @@ func (p *Parser) printStatement() (Stmt, error) {
 }
 
 func (p *Parser) blockStatement() (Stmt, error) {
+	p.beginScope()
+	defer p.endScope()
 	statements := []Stmt{}
 	for !p.check(RIGHT_BRACE) && !p.isAtEnd() {
 		stmt, err := p.declaration()
@@ -98,6 +771,11 @@ func (p *Parser) expressionStatement() (Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
+	// A match used as a statement ends with its closing brace.
+	if _, ok := expr.(*Match); ok {
+		p.match(SEMICOLON)
+		return &Expression{Expression: expr}, nil
+	}
 	_, err = p.consume(SEMICOLON, "Expect ';' after expression.")
 	if err != nil {
 		return nil, err
@@ -135,7 +813,9 @@ func (p *Parser) whileStatement() (Stmt, error) {
 		return nil, err
 	}
 	p.consume(RIGHT_PAREN, "Expect ')' after condition.")
+	p.breakDepth++
 	body, err := p.statement()
+	p.breakDepth--
 	if err != nil {
 		return nil, err
 	}
@@ -144,7 +824,12 @@ func (p *Parser) whileStatement() (Stmt, error) {
 }
 
 func (p *Parser) forStatement() (Stmt, error) {
+	keyword := p.previous()
 	p.consume(LEFT_PAREN, "Expect '(' after 'for'.")
+	if p.check(VAR) && p.isForIn() {
+		p.advance()
+		return p.forInStatement(keyword)
+	}
 	var err error
 
 	var initializer Stmt
@@ -177,7 +862,9 @@ func (p *Parser) forStatement() (Stmt, error) {
 	}
 	p.consume(RIGHT_PAREN, "Expect ')' after loop clauses.")
 
+	p.breakDepth++
 	body, err := p.statement()
+	p.breakDepth--
 	if err != nil {
 		return nil, err
 	}
@@ -190,30 +877,179 @@ func (p *Parser) forStatement() (Stmt, error) {
 	}, nil
 }
 
+func (p *Parser) forInStatement(keyword *Token) (Stmt, error) {
+	pattern, err := p.pattern()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(IN, "Expect 'in' after loop variable."); err != nil {
+		return nil, err
+	}
+	iterable, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(RIGHT_PAREN, "Expect ')' after loop iterable."); err != nil {
+		return nil, err
+	}
+
+	p.beginScope()
+	defer p.endScope()
+	for _, name := range pattern.Names() {
+		if err := p.declare(name, false); err != nil {
+			return nil, err
+		}
+	}
+	p.breakDepth++
+	body, err := p.statement()
+	p.breakDepth--
+	if err != nil {
+		return nil, err
+	}
+
+	return &ForIn{Keyword: keyword, Pattern: pattern, Iterable: iterable, Body: body}, nil
+}
+
+// isForIn reports whether the var after a for loop's opening parenthesis
+// declares the variable of a for-in loop rather than an initializer.
+func (p *Parser) isForIn() bool {
+	for n := p.current + 1; n < len(p.tokens); n++ {
+		switch p.tokens[n].Type {
+		case IN:
+			return true
+		case EQUAL, SEMICOLON, RIGHT_PAREN, EOF:
+			return false
+		}
+	}
+	return false
+}
+
 func (p *Parser) expression() (Expr, error) {
 	return p.assignment()
 }
 
 func (p *Parser) assignment() (Expr, error) {
-	expr, err := p.equality()
+	expr, err := p.conditional()
 	if err != nil {
 		return nil, err
 	}
 
 	if p.match(EQUAL) {
 		equals := p.previous()
-		value, err := p.equality()
+		value, err := p.conditional()
 		if err != nil {
 			return nil, err
 		}
 
 		if varExpr, ok := expr.(*Variable); ok {
+			if err := p.checkAssignable(varExpr.Name); err != nil {
+				return nil, err
+			}
 			return &Assign{Name: varExpr.Name, Value: value}, nil
 		}
+		if getExpr, ok := expr.(*Get); ok {
+			return &Set{Object: getExpr.Object, Name: getExpr.Name, Value: value}, nil
+		}
 
 		return nil, p.error(equals, "Invalid assignment target.")
 	}
 
+	if p.match(PLUS_EQUAL, MINUS_EQUAL, STAR_EQUAL, SLASH_EQUAL, MOD_EQUAL) {
+		operator := p.previous()
+		value, err := p.conditional()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.checkTarget(expr, operator, "Invalid assignment target."); err != nil {
+			return nil, err
+		}
+		return &CompoundAssign{Target: expr, Operator: operator, Value: value}, nil
+	}
+
+	return expr, nil
+}
+
+// checkTarget reports message at operator unless expr can be the target
+// of a compound assignment or an increment.
+func (p *Parser) checkTarget(expr Expr, operator *Token, message string) error {
+	switch expr := expr.(type) {
+	case *Variable:
+		return p.checkAssignable(expr.Name)
+	case *Get:
+		return nil
+	}
+	return p.error(operator, message)
+}
+
+func (p *Parser) beginScope() {
+	p.scopes = append(p.scopes, map[string]bool{})
+}
+
+func (p *Parser) endScope() {
+	p.scopes = p.scopes[:len(p.scopes)-1]
+}
+
+// declare records name in the current scope, rejecting redeclarations
+// of constants.
+func (p *Parser) declare(name *Token, constant bool) error {
+	scope := p.scopes[len(p.scopes)-1]
+	if scope[name.Lexeme] {
+		return p.error(name, fmt.Sprintf("Cannot redeclare constant '%s'.", name.Lexeme))
+	}
+	scope[name.Lexeme] = constant
+	return nil
+}
+
+// checkAssignable rejects assignments to names declared as constants.
+// Names declared outside the source being parsed are checked at runtime.
+func (p *Parser) checkAssignable(name *Token) error {
+	for n := len(p.scopes) - 1; n >= 0; n-- {
+		if constant, ok := p.scopes[n][name.Lexeme]; ok {
+			if constant {
+				return p.error(name, fmt.Sprintf("Cannot assign to constant '%s'.", name.Lexeme))
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+func (p *Parser) conditional() (Expr, error) {
+	expr, err := p.coalesce()
+	if err != nil {
+		return nil, err
+	}
+	if p.match(QUESTION) {
+		question := p.previous()
+		thenBranch, err := p.conditional()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(COLON, "Expect ':' after then branch of conditional expression."); err != nil {
+			return nil, err
+		}
+		elseBranch, err := p.conditional()
+		if err != nil {
+			return nil, err
+		}
+		expr = &Conditional{Condition: expr, Question: question, Then: thenBranch, Else: elseBranch}
+	}
+	return expr, nil
+}
+
+func (p *Parser) coalesce() (Expr, error) {
+	expr, err := p.equality()
+	if err != nil {
+		return nil, err
+	}
+	for p.match(QUESTION_QUESTION) {
+		operator := p.previous()
+		right, err := p.equality()
+		if err != nil {
+			return nil, err
+		}
+		expr = &Logical{Left: expr, Operator: operator, Right: right}
+	}
 	return expr, nil
 }
 
@@ -234,11 +1070,75 @@ func (p *Parser) equality() (Expr, error) {
 }
 
 func (p *Parser) comparison() (Expr, error) {
-	expr, err := p.term()
+	expr, err := p.bitwiseOr()
 	if err != nil {
 		return nil, err
 	}
 	for p.match(GREATER, GREATER_EQUAL, LESS, LESS_EQUAL) {
+		operator := p.previous()
+		right, err := p.bitwiseOr()
+		if err != nil {
+			return nil, err
+		}
+		expr = &Binary{expr, operator, right}
+	}
+	return expr, nil
+}
+
+func (p *Parser) bitwiseOr() (Expr, error) {
+	expr, err := p.bitwiseXor()
+	if err != nil {
+		return nil, err
+	}
+	for p.match(PIPE) {
+		operator := p.previous()
+		right, err := p.bitwiseXor()
+		if err != nil {
+			return nil, err
+		}
+		expr = &Binary{expr, operator, right}
+	}
+	return expr, nil
+}
+
+func (p *Parser) bitwiseXor() (Expr, error) {
+	expr, err := p.bitwiseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.match(CARET) {
+		operator := p.previous()
+		right, err := p.bitwiseAnd()
+		if err != nil {
+			return nil, err
+		}
+		expr = &Binary{expr, operator, right}
+	}
+	return expr, nil
+}
+
+func (p *Parser) bitwiseAnd() (Expr, error) {
+	expr, err := p.shift()
+	if err != nil {
+		return nil, err
+	}
+	for p.match(AMPERSAND) {
+		operator := p.previous()
+		right, err := p.shift()
+		if err != nil {
+			return nil, err
+		}
+		expr = &Binary{expr, operator, right}
+	}
+	return expr, nil
+}
+
+func (p *Parser) shift() (Expr, error) {
+	expr, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.match(LESS_LESS, GREATER_GREATER) {
 		operator := p.previous()
 		right, err := p.term()
 		if err != nil {
@@ -270,7 +1170,7 @@ func (p *Parser) factor() (Expr, error) {
 	if err != nil {
 		return nil, err
 	}
-	for p.match(SLASH, STAR, MOD) {
+	for p.match(SLASH, SLASH_SLASH, STAR, MOD) {
 		operator := p.previous()
 		right, err := p.unary()
 		if err != nil {
@@ -282,7 +1182,7 @@ func (p *Parser) factor() (Expr, error) {
 }
 
 func (p *Parser) unary() (Expr, error) {
-	if p.match(BANG, MINUS) {
+	if p.match(BANG, MINUS, TILDE) {
 		operator := p.previous()
 		right, err := p.unary()
 		if err != nil {
@@ -290,17 +1190,139 @@ func (p *Parser) unary() (Expr, error) {
 		}
 		return &Unary{operator, right}, nil
 	}
-	return p.primary()
+	if p.match(PLUS_PLUS, MINUS_MINUS) {
+		operator := p.previous()
+		target, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.checkTarget(target, operator, "Invalid increment target."); err != nil {
+			return nil, err
+		}
+		return &Update{Target: target, Operator: operator, Prefix: true}, nil
+	}
+	return p.power()
 }
 
-func (p *Parser) bitwiseAnd() (Expr, error) {
-	expr, err := p.equality()
+// power parses the right operand of ** with unary so that the operator is
+// right-associative and -2 ** 2 negates the power.
+func (p *Parser) power() (Expr, error) {
+	expr, err := p.postfix()
 	if err != nil {
 		return nil, err
 	}
+	if p.match(STAR_STAR) {
+		operator := p.previous()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		expr = &Binary{expr, operator, right}
+	}
 	return expr, nil
 }
 
+func (p *Parser) postfix() (Expr, error) {
+	expr, err := p.call()
+	if err != nil {
+		return nil, err
+	}
+	if p.match(PLUS_PLUS, MINUS_MINUS) {
+		operator := p.previous()
+		if err := p.checkTarget(expr, operator, "Invalid increment target."); err != nil {
+			return nil, err
+		}
+		return &Update{Target: expr, Operator: operator}, nil
+	}
+	return expr, nil
+}
+
+func (p *Parser) call() (Expr, error) {
+	expr, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	optional := false
+	for {
+		if p.match(LEFT_PAREN) {
+			expr, err = p.finishCall(expr, false)
+			if err != nil {
+				return nil, err
+			}
+		} else if p.match(DOT) {
+			name, err := p.consume(IDENTIFIER, "Expect property name after '.'.")
+			if err != nil {
+				return nil, err
+			}
+			expr = &Get{Object: expr, Name: name}
+		} else if p.match(QUESTION_DOT) {
+			optional = true
+			if p.match(LEFT_PAREN) {
+				expr, err = p.finishCall(expr, true)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			name, err := p.consume(IDENTIFIER, "Expect property name after '?.'.")
+			if err != nil {
+				return nil, err
+			}
+			expr = &Get{Object: expr, Name: name, Optional: true}
+		} else {
+			break
+		}
+	}
+
+	if optional {
+		return &OptionalChain{Expression: expr}, nil
+	}
+	return expr, nil
+}
+
+func (p *Parser) finishCall(callee Expr, optional bool) (Expr, error) {
+	arguments := []Expr{}
+	names := []*Token{}
+	named := false
+	if !p.check(RIGHT_PAREN) {
+		for {
+			if len(arguments) >= 255 {
+				return nil, p.error(p.peek(), "Can't have more than 255 arguments.")
+			}
+			var name *Token
+			if p.check(IDENTIFIER) && p.checkNext(COLON) {
+				name = p.advance()
+				p.advance()
+				for _, existing := range names {
+					if existing != nil && existing.Lexeme == name.Lexeme {
+						return nil, p.error(name, fmt.Sprintf("Duplicate argument '%s'.", name.Lexeme))
+					}
+				}
+				named = true
+			} else if named {
+				return nil, p.error(p.peek(), "Positional argument cannot follow named arguments.")
+			}
+			arg, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			arguments = append(arguments, arg)
+			names = append(names, name)
+			if !p.match(COMMA) {
+				break
+			}
+		}
+	}
+	paren, err := p.consume(RIGHT_PAREN, "Expect ')' after arguments.")
+	if err != nil {
+		return nil, err
+	}
+	if !named {
+		names = nil
+	}
+	return &Call{Callee: callee, Paren: paren, Arguments: arguments, Names: names, Optional: optional}, nil
+}
+
 func (p *Parser) primary() (Expr, error) {
 	if p.match(IDENTIFIER) {
 		return &Variable{Name: p.previous()}, nil
@@ -314,9 +1336,21 @@ func (p *Parser) primary() (Expr, error) {
 	if p.match(NIL) {
 		return &Literal{Value: nil}, nil
 	}
-	if p.match(NUMBER, STRING) {
+	if p.match(NUMBER) {
+		return &Literal{Value: p.previous().Literal, Lexeme: p.previous().Lexeme}, nil
+	}
+	if p.match(STRING) {
 		return &Literal{Value: p.previous().Literal}, nil
 	}
+	if p.match(FUN) {
+		return p.function(p.previous())
+	}
+	if p.match(MATCH) {
+		return p.matchExpression()
+	}
+	if p.check(LEFT_PAREN) && p.isArrow() {
+		return p.arrowFunction()
+	}
 	if p.match(LEFT_PAREN) {
 		expr, err := p.expression()
 		if err != nil {
@@ -330,6 +1364,145 @@ func (p *Parser) primary() (Expr, error) {
 	return nil, p.error(p.peek(), "Expect expression.")
 }
 
+// function parses the parameters and body of a fun expression.
+func (p *Parser) function(keyword *Token) (Expr, error) {
+	if _, err := p.consume(LEFT_PAREN, "Expect '(' before parameters."); err != nil {
+		return nil, err
+	}
+	p.beginScope()
+	defer p.endScope()
+	params, err := p.parameters()
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.functionBody()
+	if err != nil {
+		return nil, err
+	}
+	return &Lambda{Keyword: keyword, Params: params, Body: body}, nil
+}
+
+// arrowFunction parses (params) => body, where body is either a block or
+// a single expression whose value is returned.
+func (p *Parser) arrowFunction() (Expr, error) {
+	p.advance()
+	p.beginScope()
+	defer p.endScope()
+	params, err := p.parameters()
+	if err != nil {
+		return nil, err
+	}
+	arrow, err := p.consume(ARROW, "Expect '=>' after parameters.")
+	if err != nil {
+		return nil, err
+	}
+	if p.check(LEFT_BRACE) {
+		body, err := p.functionBody()
+		if err != nil {
+			return nil, err
+		}
+		return &Lambda{Keyword: arrow, Params: params, Body: body}, nil
+	}
+
+	value, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	return &Lambda{Keyword: arrow, Params: params, Body: []Stmt{&Return{Keyword: arrow, Value: value}}}, nil
+}
+
+// parameters parses a parameter list up to and including the closing
+// parenthesis.
+func (p *Parser) parameters() ([]*Parameter, error) {
+	params := []*Parameter{}
+	if !p.check(RIGHT_PAREN) {
+		for {
+			if len(params) >= 255 {
+				return nil, p.error(p.peek(), "Can't have more than 255 parameters.")
+			}
+			rest := p.match(DOT_DOT_DOT)
+			param := &Parameter{Rest: rest}
+			if !rest && (p.check(LEFT_BRACKET) || p.check(LEFT_BRACE)) {
+				pattern, err := p.pattern()
+				if err != nil {
+					return nil, err
+				}
+				param.Pattern = pattern
+			} else {
+				name, err := p.consume(IDENTIFIER, "Expect parameter name.")
+				if err != nil {
+					return nil, err
+				}
+				param.Name = name
+			}
+			for _, name := range param.names() {
+				for _, existing := range params {
+					for _, existingName := range existing.names() {
+						if existingName.Lexeme == name.Lexeme {
+							return nil, p.error(name, fmt.Sprintf("Duplicate parameter '%s'.", name.Lexeme))
+						}
+					}
+				}
+			}
+			if p.match(EQUAL) {
+				if rest {
+					return nil, p.error(p.previous(), "Rest parameter cannot have a default value.")
+				}
+				var err error
+				if param.Default, err = p.expression(); err != nil {
+					return nil, err
+				}
+			}
+			params = append(params, param)
+			for _, name := range param.names() {
+				p.declare(name, false)
+			}
+			if !p.match(COMMA) {
+				break
+			}
+			if rest {
+				return nil, p.error(p.previous(), "Rest parameter must be last.")
+			}
+		}
+	}
+	if _, err := p.consume(RIGHT_PAREN, "Expect ')' after parameters."); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+func (p *Parser) functionBody() ([]Stmt, error) {
+	p.functionDepth++
+	breakDepth, deferDepth := p.breakDepth, p.deferDepth
+	p.breakDepth, p.deferDepth = 0, 0
+	defer func() { p.functionDepth--; p.breakDepth, p.deferDepth = breakDepth, deferDepth }()
+	body, err := p.block("Expect '{' before function body.")
+	if err != nil {
+		return nil, err
+	}
+	return body.Statements, nil
+}
+
+// isArrow reports whether the parenthesis at the current token opens the
+// parameter list of an arrow lambda rather than a grouping.
+func (p *Parser) isArrow() bool {
+	depth := 0
+	for n := p.current; n < len(p.tokens); n++ {
+		switch p.tokens[n].Type {
+		case LEFT_PAREN:
+			depth++
+		case RIGHT_PAREN:
+			depth--
+			if depth == 0 {
+				return n+1 < len(p.tokens) && p.tokens[n+1].Type == ARROW
+			}
+		case EOF:
+			return false
+		}
+	}
+	return false
+}
+
 func (p *Parser) match(types ...TokenType) bool {
 	for _, t := range types {
 		if p.check(t) {
@@ -347,6 +1520,13 @@ func (p *Parser) check(tokenType TokenType) bool {
 	return p.peek().Type == tokenType
 }
 
+func (p *Parser) checkNext(tokenType TokenType) bool {
+	if p.isAtEnd() || p.current+1 >= len(p.tokens) {
+		return false
+	}
+	return p.tokens[p.current+1].Type == tokenType
+}
+
 func (p *Parser) advance() *Token {
 	if !p.isAtEnd() {
 		p.current++
@@ -374,8 +1554,17 @@ func (p *Parser) consume(tokenType TokenType, message string) (*Token, error) {
 }
 
 func (p *Parser) error(token *Token, message string) error {
-	parseError(token, message)
-	return fmt.Errorf("parse error at '%s': %s", token.Lexeme, message)
+	if token.Type == EOF {
+		fmt.Fprintf(p.errOut, "[line %d] Error at end: %s\n", token.Line, message)
+	} else {
+		fmt.Fprintf(p.errOut, "[line %d] Error at '%s': %s\n", token.Line, token.Lexeme, message)
+	}
+	return &ParseError{Token: token, Message: message}
+}
+
+// warning reports a problem that does not stop the script from running.
+func (p *Parser) warning(token *Token, message string) {
+	fmt.Fprintf(p.errOut, "[line %d] Warning at '%s': %s\n", token.Line, token.Lexeme, message)
 }
 
 func (p *Parser) synchronize() {
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pattern is the target of a destructuring declaration or parameter, or
+// a pattern in a match arm. It is either a single Name, a list pattern
+// [a, b, ...rest] whose Elements are patterns themselves, or a map
+// pattern {a, b} listing Keys. Match arms can also use a literal Value,
+// a Wildcard, Alternatives joined by | and map keys with a pattern of
+// their own in Fields.
+type Pattern struct {
+	Name         *Token
+	Bracket      *Token
+	Elements     []*Pattern
+	Rest         *Token
+	Keys         []*Token
+	Fields       []*Pattern
+	Value        Expr
+	Wildcard     bool
+	Alternatives []*Pattern
+}
+
+// Names returns every variable the pattern binds, in order.
+func (p *Pattern) Names() []*Token {
+	switch {
+	case p.Name != nil:
+		return []*Token{p.Name}
+	case p.Value != nil, p.Wildcard, p.Alternatives != nil:
+		return nil
+	}
+
+	var names []*Token
+	for n, key := range p.Keys {
+		if p.field(n) != nil {
+			names = append(names, p.field(n).Names()...)
+		} else {
+			names = append(names, key)
+		}
+	}
+	for _, element := range p.Elements {
+		names = append(names, element.Names()...)
+	}
+	if p.Rest != nil {
+		names = append(names, p.Rest)
+	}
+	return names
+}
+
+// field returns the pattern for the nth map key, or nil if the key just
+// binds a variable of the same name.
+func (p *Pattern) field(n int) *Pattern {
+	if n < len(p.Fields) {
+		return p.Fields[n]
+	}
+	return nil
+}
+
+// isIrrefutable reports whether the pattern matches every value.
+func (p *Pattern) isIrrefutable() bool {
+	if p.Wildcard || p.Name != nil {
+		return true
+	}
+	for _, alternative := range p.Alternatives {
+		if alternative.isIrrefutable() {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Pattern) String() string {
+	switch {
+	case p.Name != nil:
+		return p.Name.Lexeme
+	case p.Wildcard:
+		return "_"
+	case p.Value != nil:
+		value, _ := p.Value.Accept(&AstPrinter{})
+		return value.(string)
+	}
+
+	var parts []string
+	if p.Alternatives != nil {
+		for _, alternative := range p.Alternatives {
+			parts = append(parts, alternative.String())
+		}
+		return strings.Join(parts, " | ")
+	}
+	if p.Keys != nil {
+		for n, key := range p.Keys {
+			if p.field(n) != nil {
+				parts = append(parts, key.Lexeme+": "+p.field(n).String())
+			} else {
+				parts = append(parts, key.Lexeme)
+			}
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	for _, element := range p.Elements {
+		parts = append(parts, element.String())
+	}
+	if p.Rest != nil {
+		parts = append(parts, "..."+p.Rest.Lexeme)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// destructure matches value against pattern and defines the variables it
+// binds in environment.
+func (i *Interpreter) destructure(pattern *Pattern, value interface{}, environment *Environment) error {
+	if pattern.Name != nil {
+		environment.Define(pattern.Name.Lexeme, value)
+		return nil
+	}
+
+	if pattern.Keys != nil {
+		object, ok := value.(Object)
+		if !ok {
+			return i.error(pattern.Bracket, fmt.Sprintf("Cannot destructure %s as a map.", typeName(value)))
+		}
+		for _, key := range pattern.Keys {
+			if m, ok := object.(*Map); ok {
+				if _, ok := m.Entries[key.Lexeme]; !ok {
+					return i.error(key, fmt.Sprintf("Map has no key '%s'.", key.Lexeme))
+				}
+			}
+			field, err := object.Get(key)
+			if err != nil {
+				return i.error(key, err.Error())
+			}
+			environment.Define(key.Lexeme, field)
+		}
+		return nil
+	}
+
+	list, ok := value.(*List)
+	if !ok {
+		return i.error(pattern.Bracket, fmt.Sprintf("Cannot destructure %s as a list.", typeName(value)))
+	}
+	if pattern.Rest == nil && len(list.Elements) != len(pattern.Elements) {
+		return i.error(pattern.Bracket, fmt.Sprintf("Expected %d elements but got %d.", len(pattern.Elements), len(list.Elements)))
+	}
+	if len(list.Elements) < len(pattern.Elements) {
+		return i.error(pattern.Bracket, fmt.Sprintf("Expected at least %d elements but got %d.", len(pattern.Elements), len(list.Elements)))
+	}
+	for n, element := range pattern.Elements {
+		if err := i.destructure(element, list.Elements[n], environment); err != nil {
+			return err
+		}
+	}
+	if pattern.Rest != nil {
+		rest := append([]interface{}{}, list.Elements[len(pattern.Elements):]...)
+		environment.Define(pattern.Rest.Lexeme, NewList(rest))
+	}
+	return nil
+}
+
+// matches reports whether value has the shape of pattern, defining the
+// variables the pattern binds in environment when it does.
+func (i *Interpreter) matches(pattern *Pattern, value interface{}, environment *Environment) (bool, error) {
+	switch {
+	case pattern.Wildcard:
+		return true, nil
+	case pattern.Name != nil:
+		environment.Define(pattern.Name.Lexeme, value)
+		return true, nil
+	case pattern.Value != nil:
+		expected, err := i.evaluate(pattern.Value)
+		if err != nil {
+			return false, err
+		}
+		return i.isEqual(expected, value), nil
+	case pattern.Alternatives != nil:
+		for _, alternative := range pattern.Alternatives {
+			if ok, err := i.matches(alternative, value, environment); ok || err != nil {
+				return ok, err
+			}
+		}
+		return false, nil
+	case pattern.Keys != nil:
+		object, ok := value.(Object)
+		if !ok {
+			return false, nil
+		}
+		for n, key := range pattern.Keys {
+			if m, ok := object.(*Map); ok {
+				if _, ok := m.Entries[key.Lexeme]; !ok {
+					return false, nil
+				}
+			}
+			field, err := object.Get(key)
+			if err != nil {
+				return false, nil
+			}
+			if pattern.field(n) == nil {
+				environment.Define(key.Lexeme, field)
+				continue
+			}
+			if ok, err := i.matches(pattern.field(n), field, environment); !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	list, ok := value.(*List)
+	if !ok {
+		return false, nil
+	}
+	if len(list.Elements) < len(pattern.Elements) || (pattern.Rest == nil && len(list.Elements) != len(pattern.Elements)) {
+		return false, nil
+	}
+	for n, element := range pattern.Elements {
+		if ok, err := i.matches(element, list.Elements[n], environment); !ok || err != nil {
+			return false, err
+		}
+	}
+	if pattern.Rest != nil {
+		rest := append([]interface{}{}, list.Elements[len(pattern.Elements):]...)
+		environment.Define(pattern.Rest.Lexeme, NewList(rest))
+	}
+	return true, nil
+}
+
+func (i *Interpreter) VisitMatchExpr(expr *Match) (interface{}, error) {
+	subject, err := i.evaluate(expr.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, arm := range expr.Arms {
+		environment := NewEnvironment(i.environment)
+		previous := i.environment
+		i.environment = environment
+		ok, err := i.matches(arm.Pattern, subject, environment)
+		if err == nil && ok && arm.Guard != nil {
+			var guard interface{}
+			guard, err = i.evaluate(arm.Guard)
+			ok = i.isTruthy(guard)
+		}
+		i.environment = previous
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		if arm.Body != nil {
+			return nil, i.executeBlock(arm.Body.Statements, environment)
+		}
+		return i.evaluateIn(arm.Value, environment)
+	}
+	return nil, i.error(expr.Keyword, fmt.Sprintf("No match arm matches %s.", i.stringify(subject)))
+}
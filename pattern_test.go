@@ -0,0 +1,123 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDestructuring(t *testing.T) {
+	interpreter := NewInterpreter(Options{})
+	interpreter.Define("scores", []int{1, 2, 3, 4})
+	interpreter.Define("person", map[string]interface{}{"name": "Ada", "age": 36})
+	stdout, stderr, err := runScript(interpreter, `
+		var [first, second, ...others] = scores;
+		print first + second;
+		print others;
+		var {name, age} = person;
+		print name;
+		fun sum([a, b]) { return a + b; }
+		print sum(others);
+	`)
+	if err != nil {
+		t.Fatalf("Run() = %v, stderr %q", err, stderr)
+	}
+	if want := "3\n[3, 4]\nAda\n7\n"; stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+}
+
+func TestForIn(t *testing.T) {
+	interpreter := NewInterpreter(Options{})
+	interpreter.Define("pairs", [][]int{{1, 2}, {3, 4}})
+	interpreter.Define("ages", map[string]int{"bob": 25, "ada": 36})
+	interpreter.Define("people", []map[string]interface{}{{"name": "Ada"}, {"name": "Bob"}})
+	stdout, stderr, err := runScript(interpreter, `
+		for (var [a, b] in pairs) print a * b;
+		for (var [name, age] in ages) print name + " " + (age // 10 * 10 == 30 ? "30s" : "20s");
+		for (var {name} in people) {
+			if (name == "Bob") break;
+			print name;
+		}
+		var last;
+		for (var pair in pairs) last = pair;
+		print last;
+	`)
+	if err != nil {
+		t.Fatalf("Run() = %v, stderr %q", err, stderr)
+	}
+	if want := "2\n12\nada 30s\nbob 20s\nAda\n[3, 4]\n"; stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+}
+
+func TestForInErrors(t *testing.T) {
+	interpreter := NewInterpreter(Options{})
+	interpreter.Define("pairs", [][]int{{1, 2}, {3}})
+	tests := []struct {
+		source string
+		stderr string
+	}{
+		{`for (var x in 42) print x;`, "Cannot iterate over number."},
+		{`for (var [a, b] in pairs) print a;`, "Expected 2 elements but got 1."},
+	}
+	for _, test := range tests {
+		_, stderr, err := runScript(interpreter, test.source)
+		if err == nil || !strings.Contains(stderr, test.stderr) {
+			t.Errorf("%s: Run() = %v, stderr %q, want %q", test.source, err, stderr, test.stderr)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		value  interface{}
+		source string
+		stdout string
+	}{
+		{1, `print match (value) { 0 | 1 => "tiny", _ => "big" };`, "tiny\n"},
+		{-5, `print match (value) { n if n < 0 => "negative", _ => "other" };`, "negative\n"},
+		{5, `print match (value) { n if n < 0 => "negative", n => n * 10 };`, "50\n"},
+		{[]int{3, 4}, `print match (value) { [x, y] if x > y => "down", [x, y] => y };`, "4\n"},
+		{[]int{3}, `print match (value) { [x, y] => "pair", [x] => x, _ => "other" };`, "3\n"},
+		{[]interface{}{1, []int{2, 3, 4}}, `print match (value) { [a, [b, ...rest]] => rest, _ => nil };`, "[3, 4]\n"},
+		{
+			map[string]interface{}{"kind": "circle", "center": map[string]int{"x": 1, "y": 2}},
+			`print match (value) { {kind: "square"} => "square", {kind: "circle", center: {x, y}} => x + y, _ => 0 };`,
+			"3\n",
+		},
+		{[]int{6, 7}, `var result = match (value) { [x, y] => { print x * y; }, _ => 0 }; print result;`, "42\nnil\n"},
+		{"a", `var x = "outer"; match (value) { x => x, }; print x;`, "outer\n"},
+	}
+	for _, test := range tests {
+		interpreter := NewInterpreter(Options{})
+		if err := interpreter.Define("value", test.value); err != nil {
+			t.Fatal(err)
+		}
+		stdout, stderr, err := runScript(interpreter, test.source)
+		if err != nil || stdout != test.stdout {
+			t.Errorf("%s: Run() = %v, stdout %q, want %q (stderr %q)", test.source, err, stdout, test.stdout, stderr)
+		}
+	}
+}
+
+func TestMatchWithoutMatchingArm(t *testing.T) {
+	tests := []struct {
+		value  interface{}
+		source string
+		stderr string
+	}{
+		{7, `print match (value) { 1 => "one" };`, "No match arm matches 7."},
+		{[]int{1, 2}, `print match (value) { [x] => x };`, "No match arm matches [1, 2]."},
+		{-1, `print match (value) { n if n > 0 => n };`, "No match arm matches -1."},
+	}
+	for _, test := range tests {
+		interpreter := NewInterpreter(Options{})
+		if err := interpreter.Define("value", test.value); err != nil {
+			t.Fatal(err)
+		}
+		stdout, stderr, err := runScript(interpreter, test.source)
+		if err == nil || stdout != "" || !strings.Contains(stderr, test.stderr) {
+			t.Errorf("%s: Run() = %v, stdout %q, stderr %q, want %q", test.source, err, stdout, stderr, test.stderr)
+		}
+	}
+}
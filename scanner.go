@@ -1,34 +1,68 @@
 package main
 
 import (
+	"fmt"
+	"io"
+	"os"
 	"strconv"
+	"strings"
 )
 
+type ScanError struct {
+	Line    int
+	Message string
+}
+
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("scan error at line %d: %s", e.Line, e.Message)
+}
+
 type Scanner struct {
 	source  string
 	tokens  []Token
 	start   int
 	current int
 	line    int
+	errOut  io.Writer
+	// err is the first lexical error found, so callers can refuse to run
+	// a source that did not scan cleanly.
+	err error
 }
 
 var keywords = map[string]TokenType{
-	"and":    AND,
-	"class":  CLASS,
-	"else":   ELSE,
-	"false":  FALSE,
-	"for":    FOR,
-	"fun":    FUN,
-	"if":     IF,
-	"nil":    NIL,
-	"or":     OR,
-	"print":  PRINT,
-	"return": RETURN,
-	"super":  SUPER,
-	"this":   THIS,
-	"true":   TRUE,
-	"var":    VAR,
-	"while":  WHILE,
+	"and":         AND,
+	"as":          AS,
+	"break":       BREAK,
+	"case":        CASE,
+	"catch":       CATCH,
+	"class":       CLASS,
+	"const":       CONST,
+	"default":     DEFAULT,
+	"defer":       DEFER,
+	"else":        ELSE,
+	"export":      EXPORT,
+	"fallthrough": FALLTHROUGH,
+	"false":       FALSE,
+	"finally":     FINALLY,
+	"for":         FOR,
+	"fun":         FUN,
+	"if":          IF,
+	"import":      IMPORT,
+	"in":          IN,
+	"let":         LET,
+	"match":       MATCH,
+	"nil":         NIL,
+	"or":          OR,
+	"print":       PRINT,
+	"return":      RETURN,
+	"super":       SUPER,
+	"switch":      SWITCH,
+	"this":        THIS,
+	"throw":       THROW,
+	"true":        TRUE,
+	"try":         TRY,
+	"var":         VAR,
+	"while":       WHILE,
 }
 
 func NewScanner(source string) *Scanner {
@@ -38,9 +72,15 @@ func NewScanner(source string) *Scanner {
 		start:   0,
 		current: 0,
 		line:    1,
+		errOut:  os.Stderr,
 	}
 }
 
+// SetErrorOutput sets where lexical errors are reported.
+func (s *Scanner) SetErrorOutput(w io.Writer) {
+	s.errOut = w
+}
+
 func (s *Scanner) ScanTokens() []Token {
 	for !s.isAtEnd() {
 		s.start = s.current
@@ -51,6 +91,12 @@ func (s *Scanner) ScanTokens() []Token {
 	return s.tokens
 }
 
+// Err returns the first lexical error found by ScanTokens, or nil if the
+// source scanned cleanly. Every error is also reported as it is found.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
 func (s *Scanner) scanToken() {
 	c := s.advance()
 	switch c {
@@ -62,20 +108,70 @@ func (s *Scanner) scanToken() {
 		s.addToken(LEFT_BRACE)
 	case '}':
 		s.addToken(RIGHT_BRACE)
+	case '[':
+		s.addToken(LEFT_BRACKET)
+	case ']':
+		s.addToken(RIGHT_BRACKET)
 	case ',':
 		s.addToken(COMMA)
 	case '.':
-		s.addToken(DOT)
+		if s.peek() == '.' && s.peekNext() == '.' {
+			s.advance()
+			s.advance()
+			s.addToken(DOT_DOT_DOT)
+		} else {
+			s.addToken(DOT)
+		}
 	case '-':
-		s.addToken(MINUS)
+		if s.match('=') {
+			s.addToken(MINUS_EQUAL)
+		} else if s.match('-') {
+			s.addToken(MINUS_MINUS)
+		} else {
+			s.addToken(MINUS)
+		}
 	case '+':
-		s.addToken(PLUS)
+		if s.match('=') {
+			s.addToken(PLUS_EQUAL)
+		} else if s.match('+') {
+			s.addToken(PLUS_PLUS)
+		} else {
+			s.addToken(PLUS)
+		}
 	case ';':
 		s.addToken(SEMICOLON)
+	case ':':
+		s.addToken(COLON)
+	case '?':
+		if s.match('?') {
+			s.addToken(QUESTION_QUESTION)
+		} else if s.match('.') {
+			s.addToken(QUESTION_DOT)
+		} else {
+			s.addToken(QUESTION)
+		}
 	case '*':
-		s.addToken(STAR)
+		if s.match('*') {
+			s.addToken(STAR_STAR)
+		} else if s.match('=') {
+			s.addToken(STAR_EQUAL)
+		} else {
+			s.addToken(STAR)
+		}
 	case '%':
-		s.addToken(MOD)
+		if s.match('=') {
+			s.addToken(MOD_EQUAL)
+		} else {
+			s.addToken(MOD)
+		}
+	case '&':
+		s.addToken(AMPERSAND)
+	case '|':
+		s.addToken(PIPE)
+	case '^':
+		s.addToken(CARET)
+	case '~':
+		s.addToken(TILDE)
 	case '!':
 		if s.match('=') {
 			s.addToken(BANG_EQUAL)
@@ -85,26 +181,37 @@ func (s *Scanner) scanToken() {
 	case '=':
 		if s.match('=') {
 			s.addToken(EQUAL_EQUAL)
+		} else if s.match('>') {
+			s.addToken(ARROW)
 		} else {
 			s.addToken(EQUAL)
 		}
 	case '<':
 		if s.match('=') {
 			s.addToken(LESS_EQUAL)
+		} else if s.match('<') {
+			s.addToken(LESS_LESS)
 		} else {
 			s.addToken(LESS)
 		}
 	case '>':
 		if s.match('=') {
 			s.addToken(GREATER_EQUAL)
+		} else if s.match('>') {
+			s.addToken(GREATER_GREATER)
 		} else {
 			s.addToken(GREATER)
 		}
 	case '/':
-		if s.match('/') {
+		if s.peek() == '/' && s.followsOperand() {
+			s.advance()
+			s.addToken(SLASH_SLASH)
+		} else if s.match('/') {
 			for s.peek() != '\n' && !s.isAtEnd() {
 				s.advance()
 			}
+		} else if s.match('=') {
+			s.addToken(SLASH_EQUAL)
 		} else {
 			s.addToken(SLASH)
 		}
@@ -120,11 +227,29 @@ func (s *Scanner) scanToken() {
 		} else if s.isAlpha(c) {
 			s.identifier()
 		} else {
-			Error(s.line, "Unexpected character.")
+			s.error("Unexpected character.")
 		}
 	}
 }
 
+// followsOperand reports whether the last token ends an operand on the
+// current line. A "//" there is floor division rather than a comment, so
+// a comment after an expression needs a line or a ';' before it.
+func (s *Scanner) followsOperand() bool {
+	if len(s.tokens) == 0 {
+		return false
+	}
+	last := s.tokens[len(s.tokens)-1]
+	if last.Line != s.line {
+		return false
+	}
+	switch last.Type {
+	case NUMBER, STRING, IDENTIFIER, RIGHT_PAREN, TRUE, FALSE, NIL, THIS:
+		return true
+	}
+	return false
+}
+
 func (s *Scanner) advance() byte {
 	s.current++
 	return s.source[s.current-1]
@@ -173,7 +298,7 @@ func (s *Scanner) string() {
 	}
 
 	if s.isAtEnd() {
-		Error(s.line, "Unterminated string.")
+		s.error("Unterminated string.")
 		return
 	}
 
@@ -184,26 +309,141 @@ func (s *Scanner) string() {
 }
 
 func (s *Scanner) number() {
-	for s.isDigit(s.peek()) {
-		s.advance()
+	if s.source[s.start] == '0' {
+		switch s.peek() {
+		case 'x', 'X':
+			s.radixNumber(16, "hexadecimal")
+			return
+		case 'o', 'O':
+			s.radixNumber(8, "octal")
+			return
+		case 'b', 'B':
+			s.radixNumber(2, "binary")
+			return
+		}
+	}
+
+	if !s.digits(10, true) {
+		return
 	}
 
+	isFloat := false
 	if s.peek() == '.' && s.isDigit(s.peekNext()) {
+		isFloat = true
 		s.advance()
+		if !s.digits(10, false) {
+			return
+		}
+	}
 
-		for s.isDigit(s.peek()) {
+	if s.peek() == 'e' || s.peek() == 'E' {
+		isFloat = true
+		s.advance()
+		if s.peek() == '+' || s.peek() == '-' {
 			s.advance()
 		}
+		if !s.isDigit(s.peek()) {
+			s.numberError("Missing digits in exponent.")
+			return
+		}
+		if !s.digits(10, false) {
+			return
+		}
 	}
 
-	value, err := strconv.ParseFloat(s.source[s.start:s.current], 64)
+	if s.isAlphaNumeric(s.peek()) {
+		s.numberError(fmt.Sprintf("Invalid character '%c' in number.", s.peek()))
+		return
+	}
+
+	text := strings.ReplaceAll(s.source[s.start:s.current], "_", "")
+	if !isFloat {
+		value, ok := parseInteger(text, 10)
+		if !ok {
+			s.error("Invalid number.")
+			return
+		}
+		s.addTokenWithLiteral(NUMBER, value)
+		return
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
 	if err != nil {
-		Error(s.line, "Invalid number.")
+		s.error("Invalid number.")
+		return
+	}
+	s.addTokenWithLiteral(NUMBER, value)
+}
+
+// radixNumber scans an integer literal with a 0x, 0o or 0b prefix.
+func (s *Scanner) radixNumber(base int, name string) {
+	prefix := s.advance()
+	if !s.isDigitInBase(s.peek(), base) {
+		if s.isAlphaNumeric(s.peek()) && s.peek() != '_' {
+			s.numberError(fmt.Sprintf("Invalid digit '%c' in %s literal.", s.peek(), name))
+		} else {
+			s.numberError(fmt.Sprintf("Missing digits after '0%c'.", prefix))
+		}
+		return
+	}
+	if !s.digits(base, false) {
+		return
+	}
+	if s.isAlphaNumeric(s.peek()) {
+		s.numberError(fmt.Sprintf("Invalid digit '%c' in %s literal.", s.peek(), name))
+		return
+	}
+
+	text := strings.ReplaceAll(s.source[s.start+2:s.current], "_", "")
+	value, ok := parseInteger(text, base)
+	if !ok {
+		s.error("Invalid number.")
 		return
 	}
 	s.addTokenWithLiteral(NUMBER, value)
 }
 
+// digits consumes a run of digits in base, allowing single underscores
+// between digits. afterDigit reports whether a digit was consumed just
+// before the run.
+func (s *Scanner) digits(base int, afterDigit bool) bool {
+	underscore := false
+	for {
+		c := s.peek()
+		if c == '_' {
+			if underscore {
+				s.numberError("Consecutive '_' in number.")
+				return false
+			}
+			if !afterDigit {
+				s.numberError("'_' must separate digits.")
+				return false
+			}
+			underscore = true
+		} else if s.isDigitInBase(c, base) {
+			underscore = false
+			afterDigit = true
+		} else {
+			break
+		}
+		s.advance()
+	}
+
+	if underscore {
+		s.numberError("Number cannot end with '_'.")
+		return false
+	}
+	return true
+}
+
+// numberError reports a malformed number literal and skips the rest of it.
+func (s *Scanner) numberError(message string) {
+	s.error(message)
+	for s.isAlphaNumeric(s.peek()) || (s.peek() == '.' && s.isDigit(s.peekNext())) {
+		s.advance()
+	}
+}
+
 func (s *Scanner) identifier() {
 	for s.isAlphaNumeric(s.peek()) {
 		s.advance()
@@ -225,6 +465,18 @@ func (s *Scanner) isDigit(c byte) bool {
 	return c >= '0' && c <= '9'
 }
 
+func (s *Scanner) isDigitInBase(c byte, base int) bool {
+	switch base {
+	case 2:
+		return c == '0' || c == '1'
+	case 8:
+		return c >= '0' && c <= '7'
+	case 16:
+		return s.isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+	}
+	return s.isDigit(c)
+}
+
 func (s *Scanner) isAlpha(c byte) bool {
 	return (c >= 'a' && c <= 'z') ||
 		(c >= 'A' && c <= 'Z') ||
@@ -235,6 +487,9 @@ func (s *Scanner) isAlphaNumeric(c byte) bool {
 	return s.isAlpha(c) || s.isDigit(c)
 }
 
-func Error(line int, message string) {
-	Report(line, "", message)
+func (s *Scanner) error(message string) {
+	fmt.Fprintf(s.errOut, "[line %d] Error: %s\n", s.line, message)
+	if s.err == nil {
+		s.err = &ScanError{Line: s.line, Message: message}
+	}
 }
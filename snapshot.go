@@ -0,0 +1,237 @@
+package main
+
+// Snapshot is a frozen copy of an interpreter's global environment. Fresh
+// interpreters created from it share the frozen values and only copy the
+// mutable collections, so assignments made by one script never leak into
+// another. Go objects bound by the host are shared, not copied. Functions
+// are copied with their closures, so they see the globals of the
+// interpreter that calls them; frozen collections holding functions are
+// copied for the same reason. Imported modules are copied with their
+// environments and stay cached, so they are not run again.
+type Snapshot struct {
+	globals *Environment
+	limits  Limits
+	loader  ModuleLoader
+	modules map[string]*Module
+}
+
+// Snapshot captures the current global environment.
+func (i *Interpreter) Snapshot() *Snapshot {
+	var chain []*Environment
+	for env := i.globals; env != nil; env = env.enclosing {
+		chain = append(chain, env)
+	}
+
+	globals := NewEnvironment(nil)
+	copier := newValueCopier()
+	for _, env := range chain {
+		copier.environments[env] = globals
+	}
+	for n := len(chain) - 1; n >= 0; n-- {
+		for name, value := range chain[n].values {
+			if chain[n].constants[name] {
+				globals.DefineConstant(name, copier.copy(value))
+			} else {
+				globals.Define(name, copier.copy(value))
+			}
+		}
+	}
+	globals.frozen = true
+
+	modules := make(map[string]*Module, len(i.modules))
+	for path, module := range i.modules {
+		modules[path] = copier.copy(module).(*Module)
+	}
+
+	return &Snapshot{globals: globals, limits: i.limits, loader: i.loader, modules: modules}
+}
+
+// NewInterpreter returns an interpreter whose globals start out as the
+// snapshot's. It uses the default I/O streams and the limits of the
+// interpreter the snapshot was taken from.
+func (s *Snapshot) NewInterpreter() *Interpreter {
+	interpreter := NewInterpreter(Options{})
+	interpreter.SetLimits(s.limits)
+
+	builtins := NewEnvironment(s.globals)
+	globals := NewEnvironment(builtins)
+	copier := newValueCopier()
+	copier.environments[s.globals] = globals
+	for name, value := range s.globals.values {
+		if !copier.mustCopy(value) {
+			continue
+		}
+		if s.globals.constants[name] {
+			builtins.DefineConstant(name, copier.copy(value))
+		} else {
+			builtins.Define(name, copier.copy(value))
+		}
+	}
+	// Modules run in an environment of their own below the builtins, not
+	// the globals of the script that imports them.
+	for path, module := range s.modules {
+		copied := copier.copy(module).(*Module)
+		copied.environment.enclosing = builtins
+		interpreter.modules[path] = copied
+	}
+	interpreter.builtins = builtins
+	interpreter.globals = globals
+	interpreter.environment = interpreter.globals
+	interpreter.loader = s.loader
+	return interpreter
+}
+
+// valueCopier deep-copies Lango values, preserving aliasing and cycles
+// between the collections it copies. Environments captured by functions
+// are copied too, except those mapped in environments, which are replaced.
+type valueCopier struct {
+	seen         map[interface{}]interface{}
+	environments map[*Environment]*Environment
+	// closures records whether each frozen collection visited so far can
+	// reach a function or module, which must be copied.
+	closures map[interface{}]bool
+}
+
+func newValueCopier() *valueCopier {
+	return &valueCopier{
+		seen:         make(map[interface{}]interface{}),
+		environments: make(map[*Environment]*Environment),
+		closures:     make(map[interface{}]bool),
+	}
+}
+
+// mustCopy reports whether value must be copied. Frozen collections are
+// shared unless they reach a function or module.
+func (c *valueCopier) mustCopy(value interface{}) bool {
+	switch v := value.(type) {
+	case *List:
+		return !v.frozen || c.reachesClosure(v)
+	case *Map:
+		return !v.frozen || c.reachesClosure(v)
+	case *Function, *Module:
+		return true
+	}
+	return false
+}
+
+// reachesClosure reports whether the frozen collection value contains a
+// function or module, directly or through other collections. It walks
+// every collection reachable from value once, then marks those from
+// which a closure can be reached by following the references back.
+func (c *valueCopier) reachesClosure(value interface{}) bool {
+	if reaches, ok := c.closures[value]; ok {
+		return reaches
+	}
+
+	referrers := make(map[interface{}][]interface{})
+	var found []interface{}
+	visited := map[interface{}]bool{value: true}
+	pending := []interface{}{value}
+	for len(pending) > 0 {
+		collection := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		visit := func(element interface{}) {
+			switch element.(type) {
+			case *Function, *Module:
+				found = append(found, collection)
+			case *List, *Map:
+				if reaches, ok := c.closures[element]; ok {
+					if reaches {
+						found = append(found, collection)
+					}
+					return
+				}
+				referrers[element] = append(referrers[element], collection)
+				if !visited[element] {
+					visited[element] = true
+					pending = append(pending, element)
+				}
+			}
+		}
+		switch v := collection.(type) {
+		case *List:
+			for _, element := range v.Elements {
+				visit(element)
+			}
+		case *Map:
+			for _, entry := range v.Entries {
+				visit(entry)
+			}
+		}
+	}
+
+	for collection := range visited {
+		c.closures[collection] = false
+	}
+	for len(found) > 0 {
+		collection := found[len(found)-1]
+		found = found[:len(found)-1]
+		if c.closures[collection] {
+			continue
+		}
+		c.closures[collection] = true
+		found = append(found, referrers[collection]...)
+	}
+	return c.closures[value]
+}
+
+func (c *valueCopier) copy(value interface{}) interface{} {
+	if copied, ok := c.seen[value]; ok {
+		return copied
+	}
+
+	if !c.mustCopy(value) {
+		return value
+	}
+
+	switch v := value.(type) {
+	case *List:
+		list := NewList(make([]interface{}, len(v.Elements)))
+		list.frozen = v.frozen
+		c.seen[value] = list
+		for n, element := range v.Elements {
+			list.Elements[n] = c.copy(element)
+		}
+		return list
+	case *Map:
+		m := NewMap(make(map[string]interface{}, len(v.Entries)))
+		m.frozen = v.frozen
+		c.seen[value] = m
+		for key, entry := range v.Entries {
+			m.Entries[key] = c.copy(entry)
+		}
+		return m
+	case *Function:
+		function := &Function{declaration: v.declaration}
+		c.seen[value] = function
+		function.closure = c.environment(v.closure)
+		return function
+	case *Module:
+		module := &Module{name: v.name, path: v.path, exports: v.exports, err: v.err}
+		c.seen[value] = module
+		module.environment = c.environment(v.environment)
+		return module
+	}
+	return value
+}
+
+func (c *valueCopier) environment(env *Environment) *Environment {
+	if env == nil {
+		return nil
+	}
+	if copied, ok := c.environments[env]; ok {
+		return copied
+	}
+
+	copied := NewEnvironment(nil)
+	c.environments[env] = copied
+	copied.enclosing = c.environment(env.enclosing)
+	copied.frozen = env.frozen
+	for name, value := range env.values {
+		copied.values[name] = c.copy(value)
+		if env.constants[name] {
+			copied.DefineConstant(name, copied.values[name])
+		}
+	}
+	return copied
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSnapshotIsolatesGlobals(t *testing.T) {
+	base := NewInterpreter(Options{})
+	if _, stderr, err := runScript(base, `var count = 0; fun next() { count += 1; return count; }`); err != nil {
+		t.Fatalf("Run() = %v, stderr %q", err, stderr)
+	}
+	snapshot := base.Snapshot()
+
+	for n := 0; n < 3; n++ {
+		stdout, stderr, _ := runScript(snapshot.NewInterpreter(), `next(); print next();`)
+		if stdout != "2\n" {
+			t.Errorf("run %d: stdout = %q, want %q (stderr %q)", n, stdout, "2\n", stderr)
+		}
+	}
+}
+
+func TestSnapshotCopiesModules(t *testing.T) {
+	base := newModuleInterpreter()
+	if _, stderr, err := runScript(base, `import "lib/counter.lango" as counter;`); err != nil {
+		t.Fatalf("Run() = %v, stderr %q", err, stderr)
+	}
+	snapshot := base.Snapshot()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for n := 0; n < cap(errs); n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			stdout, stderr, _ := runScript(snapshot.NewInterpreter(), `
+				import "lib/counter.lango" as again;
+				counter.next();
+				print again.next();
+			`)
+			if stdout != "2\n" {
+				errs <- fmt.Errorf("interpreter %d: stdout = %q, want %q (stderr %q)", n, stdout, "2\n", stderr)
+			}
+		}(n)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// TestSnapshotCopiesFrozenClosures calls functions held in frozen
+// collections from interpreters running in parallel. Run it with -race to
+// check that they no longer share the globals the functions closed over.
+func TestSnapshotCopiesFrozenClosures(t *testing.T) {
+	base := NewInterpreter(Options{})
+	if err := base.Define("registry", map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, stderr, err := runScript(base, `
+		var count = 0;
+		fun next() { count += 1; return count; }
+		fun collect(...values) { return values; }
+		var handlers = freeze(collect(next));
+		var data = freeze(collect(1, collect(2, 3)));
+		registry.next = next;
+		registry.self = registry;
+		freeze(registry);
+	`); err != nil {
+		t.Fatalf("Run() = %v, stderr %q", err, stderr)
+	}
+	snapshot := base.Snapshot()
+
+	if got, want := snapshot.globals.values["data"], base.globals.values["data"]; got != want {
+		t.Errorf("frozen data without functions was copied")
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for n := 0; n < cap(errs); n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			stdout, stderr, _ := runScript(snapshot.NewInterpreter(), `
+				var [first] = handlers;
+				first();
+				registry.next();
+				print registry.self.self.next();
+			`)
+			if stdout != "3\n" {
+				errs <- fmt.Errorf("interpreter %d: stdout = %q, want %q (stderr %q)", n, stdout, "3\n", stderr)
+			}
+		}(n)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if stdout, stderr, _ := runScript(base, `print count;`); stdout != "0\n" {
+		t.Errorf("base count = %q, want %q (stderr %q)", stdout, "0\n", stderr)
+	}
+}
+
+func TestAssignToFrozenEnvironment(t *testing.T) {
+	env := NewEnvironment(nil)
+	env.Define("x", int64(1))
+	env.frozen = true
+
+	name := NewToken(IDENTIFIER, "x", nil, 1)
+	err := env.Assign(&name, int64(2))
+	if want := "Cannot assign to frozen variable 'x'."; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Assign() = %v, want %q", err, want)
+	}
+	if value, _ := env.Get(&name); value != int64(1) {
+		t.Errorf("x = %v after a rejected assignment", value)
+	}
+}
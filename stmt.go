@@ -6,11 +6,21 @@ type Stmt interface {
 
 type Visitor interface {
 	VisitBinaryExpr(*Binary) (interface{}, error)
+	VisitCallExpr(*Call) (interface{}, error)
+	VisitConditionalExpr(*Conditional) (interface{}, error)
+	VisitGetExpr(*Get) (interface{}, error)
 	VisitGroupingExpr(*Grouping) (interface{}, error)
+	VisitLambdaExpr(*Lambda) (interface{}, error)
 	VisitLiteralExpr(*Literal) (interface{}, error)
+	VisitLogicalExpr(*Logical) (interface{}, error)
+	VisitMatchExpr(*Match) (interface{}, error)
+	VisitOptionalChainExpr(*OptionalChain) (interface{}, error)
+	VisitSetExpr(*Set) (interface{}, error)
 	VisitUnaryExpr(*Unary) (interface{}, error)
 	VisitVariableExpr(*Variable) (interface{}, error)
 	VisitAssignExpr(*Assign) (interface{}, error)
+	VisitCompoundAssignExpr(*CompoundAssign) (interface{}, error)
+	VisitUpdateExpr(*Update) (interface{}, error)
 	VisitExpressionStmt(*Expression) (interface{}, error)
 	VisitPrintStmt(*Print) (interface{}, error)
 	VisitVarStmt(*Var) (interface{}, error)
@@ -18,6 +28,15 @@ type Visitor interface {
 	VisitIfStmt(*If) (interface{}, error)
 	VisitWhileStmt(*While) (interface{}, error)
 	VisitForStmt(*For) (interface{}, error)
+	VisitForInStmt(*ForIn) (interface{}, error)
+	VisitImportStmt(*Import) (interface{}, error)
+	VisitExportStmt(*Export) (interface{}, error)
+	VisitThrowStmt(*Throw) (interface{}, error)
+	VisitTryStmt(*Try) (interface{}, error)
+	VisitDeferStmt(*Defer) (interface{}, error)
+	VisitReturnStmt(*Return) (interface{}, error)
+	VisitSwitchStmt(*Switch) (interface{}, error)
+	VisitBreakStmt(*Break) (interface{}, error)
 }
 
 type Expression struct {
@@ -36,9 +55,15 @@ func (p *Print) Accept(visitor Visitor) (interface{}, error) {
 	return visitor.VisitPrintStmt(p)
 }
 
+// Var declares Name, or every name in Pattern for a destructuring
+// declaration such as var [a, b] = xs. Constant is set for const and let
+// declarations, whose keyword is kept in Keyword.
 type Var struct {
+	Keyword     *Token
 	Name        *Token
+	Pattern     *Pattern
 	Initializer Expr
+	Constant    bool
 }
 
 func (v *Var) Accept(visitor Visitor) (interface{}, error) {
@@ -82,3 +107,100 @@ type For struct {
 func (f *For) Accept(visitor Visitor) (interface{}, error) {
 	return visitor.VisitForStmt(f)
 }
+
+// ForIn runs Body once for each element of a list, or each [key, value]
+// entry of a map, binding it to Pattern.
+type ForIn struct {
+	Keyword  *Token
+	Pattern  *Pattern
+	Iterable Expr
+	Body     Stmt
+}
+
+func (f *ForIn) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitForInStmt(f)
+}
+
+type Import struct {
+	Keyword *Token
+	Path    *Token
+	Name    *Token
+}
+
+func (i *Import) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitImportStmt(i)
+}
+
+type Export struct {
+	Keyword     *Token
+	Declaration *Var
+}
+
+func (e *Export) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitExportStmt(e)
+}
+
+type Throw struct {
+	Keyword *Token
+	Value   Expr
+}
+
+func (t *Throw) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitThrowStmt(t)
+}
+
+type Try struct {
+	Body        *Block
+	CatchName   *Token
+	CatchBody   *Block
+	FinallyBody *Block
+}
+
+func (t *Try) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitTryStmt(t)
+}
+
+type Defer struct {
+	Keyword   *Token
+	Statement Stmt
+}
+
+func (d *Defer) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitDeferStmt(d)
+}
+
+type Return struct {
+	Keyword *Token
+	Value   Expr
+}
+
+func (r *Return) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitReturnStmt(r)
+}
+
+type Switch struct {
+	Keyword *Token
+	Subject Expr
+	Cases   []*Case
+}
+
+func (s *Switch) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitSwitchStmt(s)
+}
+
+// Case is a case or, when Values is nil, the default clause of a switch.
+// Fallthrough is set when the body ends with a fallthrough statement.
+type Case struct {
+	Keyword     *Token
+	Values      []Expr
+	Body        []Stmt
+	Fallthrough bool
+}
+
+type Break struct {
+	Keyword *Token
+}
+
+func (b *Break) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitBreakStmt(b)
+}
@@ -10,6 +10,8 @@ const (
 	RIGHT_PAREN
 	LEFT_BRACE
 	RIGHT_BRACE
+	LEFT_BRACKET
+	RIGHT_BRACKET
 	COMMA
 	DOT
 	MINUS
@@ -18,16 +20,37 @@ const (
 	SLASH
 	STAR
 	MOD
+	AMPERSAND
+	PIPE
+	CARET
+	TILDE
+	COLON
 
 	// One or two character tokens.
 	BANG
 	BANG_EQUAL
 	EQUAL
 	EQUAL_EQUAL
+	STAR_STAR
+	PLUS_EQUAL
+	MINUS_EQUAL
+	STAR_EQUAL
+	SLASH_SLASH
+	SLASH_EQUAL
+	MOD_EQUAL
+	PLUS_PLUS
+	MINUS_MINUS
+	QUESTION
+	QUESTION_QUESTION
+	QUESTION_DOT
+	ARROW
+	DOT_DOT_DOT
 	GREATER
 	GREATER_EQUAL
 	LESS
 	LESS_EQUAL
+	LESS_LESS
+	GREATER_GREATER
 
 	// Literals.
 	IDENTIFIER
@@ -36,19 +59,36 @@ const (
 
 	// Keywords.
 	AND
+	AS
+	BREAK
+	CASE
+	CATCH
 	CLASS
+	CONST
+	DEFAULT
+	DEFER
 	ELSE
+	EXPORT
+	FALLTHROUGH
 	FALSE
+	FINALLY
 	FUN
 	FOR
 	IF
+	IMPORT
+	IN
+	LET
+	MATCH
 	NIL
 	OR
 	PRINT
 	RETURN
 	SUPER
+	SWITCH
 	THIS
+	THROW
 	TRUE
+	TRY
 	VAR
 	WHILE
 